@@ -1,11 +1,39 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
 	_ "github.com/lib/pq"
 
 	"github.com/JohanVong/online_bazaar/internal/app"
+	"github.com/JohanVong/online_bazaar/internal/config"
 )
 
 func main() {
-	app.AssembleAndGo()
+	cfg, args, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(app.Migrate(cfg, args[1:]))
+	}
+
+	if len(args) > 0 && args[0] == "admin" {
+		os.Exit(app.Admin(cfg, args[1:]))
+	}
+
+	if err = cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	os.Exit(app.AssembleAndGo(cfg))
 }
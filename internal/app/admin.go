@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/JohanVong/online_bazaar/internal/config"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+	"github.com/JohanVong/online_bazaar/pkg/models/db"
+)
+
+const adminUsage = "Usage: api admin grant | revoke <username>"
+
+/*
+Admin() - подкоманда admin: выдает или отзывает роль admin у зарегистрированного пользователя.
+Через апи роли раздает только админ, поэтому первого админа на новой установке назначают ею.
+Отдает код выхода процесса.
+*/
+func Admin(cfg *config.Config, args []string) int {
+	if len(args) != 2 || (args[0] != "grant" && args[0] != "revoke") {
+		fmt.Fprintln(os.Stderr, adminUsage)
+		return 2
+	}
+
+	err := cfg.DB.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	conn, err := getConnDB(&cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer conn.Close()
+
+	ac := &core{users: &db.UserModel{DB: conn}, roles: &db.RoleModel{DB: conn}}
+	err = ac.setAdmin(context.Background(), args[1], args[0] == "grant")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("Role %s %sed for %s\n", models.RoleAdmin, args[0], args[1])
+	return 0
+}
+
+// setAdmin() - выдает (grant) или отзывает роль admin у пользователя по его имени
+func (ac *core) setAdmin(ctx context.Context, username string, grant bool) error {
+	uo, err := ac.users.Get(ctx, username, false)
+	if err != nil {
+		return err
+	}
+
+	if !uo.DeletedAt.IsZero() {
+		return models.ErrUserDeleted
+	}
+
+	if grant {
+		return ac.roles.Grant(ctx, uo.UserUID, models.RoleAdmin)
+	}
+
+	return ac.roles.Revoke(ctx, uo.UserUID, models.RoleAdmin)
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/internal/config"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+func TestSetAdmin(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		username string
+		grant    bool
+		wantErr  error
+	}{
+		{ // first admin of a fresh deployment
+			"TestUser",
+			true,
+			nil,
+		},
+		{ // revoke
+			"TestAdmin",
+			false,
+			nil,
+		},
+		{ // unknown user
+			"Missing",
+			true,
+			models.ErrUserNotFound,
+		},
+		{ // deleted user
+			"DeletedUser",
+			true,
+			models.ErrUserDeleted,
+		},
+	}
+
+	for _, tt := range tests {
+		err := testCore.setAdmin(context.Background(), tt.username, tt.grant)
+		assert.ErrorIs(t, err, tt.wantErr, tt.username)
+	}
+}
+
+func TestAdminUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"grant"}, {"promote", "TestUser"}, {"grant", "TestUser", "extra"}} {
+		assert.Equal(t, 2, Admin(config.Default(), args), args)
+	}
+}
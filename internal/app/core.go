@@ -1,15 +1,20 @@
 package app
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"io/ioutil"
-	"log"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 
+	"github.com/JohanVong/online_bazaar/internal/config"
+	"github.com/JohanVong/online_bazaar/internal/db/migrate"
+	"github.com/JohanVong/online_bazaar/internal/i18n"
+	"github.com/JohanVong/online_bazaar/internal/logger"
 	"github.com/JohanVong/online_bazaar/pkg/models"
 	"github.com/JohanVong/online_bazaar/pkg/models/db"
 	"github.com/JohanVong/online_bazaar/pkg/models/mock"
@@ -18,29 +23,93 @@ import (
 
 // core - ядро приложения
 type core struct {
-	sign     string
-	echo     *echo.Echo
-	infoLog  *log.Logger
-	errorLog *log.Logger
-	users    interface {
-		Insert(*models.UserSignupInput) error
-		Get(string, bool) (*models.UserOutput, error)
-		Update(string, *models.UserUpdateInput) error
-		UpdatePassword(string, *models.UpdateUserPasswordInput) error
-		Delete(string) error
+	config  *config.Config
+	catalog *i18n.Catalog
+	echo    *echo.Echo
+	log     *logger.Logger
+	metrics *appMetrics
+	// schemaVersion - версия последней миграции, вшитой в бинарник, ее ждет /readyz
+	schemaVersion int
+	// draining - не 0, когда сервер останавливается и дожидается текущих запросов
+	draining int32
+	health   interface {
+		Ping(context.Context) error
+		SchemaVersion(context.Context) (int, error)
+	}
+	hasher interface {
+		Hash(string) (string, error)
+		Verify(string, string) (bool, bool, error)
+	}
+	// dummyHash - хэш, с которым сверяется пароль несуществующего пользователя, см. missingUserHash()
+	dummyHash     string
+	dummyHashOnce sync.Once
+	users         interface {
+		Insert(context.Context, *models.UserSignupInput) error
+		Get(context.Context, string, bool) (*models.UserOutput, error)
+		Update(context.Context, string, *models.UserUpdateInput) error
+		UpdatePassword(context.Context, string, *models.UpdateUserPasswordInput) error
+		UpdateHash(context.Context, string, string) error
+		Delete(context.Context, string) error
+	}
+	tokens interface {
+		Insert(context.Context, string, string, string, time.Time) error
+		Get(context.Context, string) (*models.RefreshTokenOutput, error)
+		Rotate(context.Context, *models.RefreshTokenOutput, string, time.Time) error
+		RevokeFamily(context.Context, string) error
+		RevokeUser(context.Context, string) error
+	}
+	revocations interface {
+		Revoke(context.Context, string, time.Time) error
+		RevokeAll(context.Context, string, time.Time) error
+		IsRevoked(context.Context, string, string, time.Time) (bool, error)
+		Purge(context.Context, time.Time) (int64, error)
+	}
+	roles interface {
+		Grant(context.Context, string, string) error
+		Revoke(context.Context, string, string) error
+	}
+	shops interface {
+		Insert(context.Context, string, *models.ShopInput) (string, error)
+		Get(context.Context, string) (*models.ShopOutput, error)
+		GetList(context.Context, string) ([]*models.ShopOutput, error)
+		Update(context.Context, string, *models.ShopUpdateInput) error
+		Delete(context.Context, string) error
+	}
+	items interface {
+		Insert(context.Context, *models.ItemInput) (string, error)
+		Get(context.Context, string) (*models.ItemOutput, error)
+		GetList(context.Context, string) ([]*models.ItemOutput, error)
+		Search(context.Context, *models.ItemSearchInput) (*models.ItemSearchOutput, error)
+		Update(context.Context, string, *models.ItemUpdateInput) error
+		Restock(context.Context, string, models.Decimal) error
+		Delete(context.Context, string) error
+	}
+	carts interface {
+		Get(context.Context, string) (*models.CartOutput, error)
+		Put(context.Context, string, string, models.Decimal, string) error
+		Add(context.Context, string, string, models.Decimal, string) error
+		Remove(context.Context, string, string) error
+		Clear(context.Context, string) error
+	}
+	orders interface {
+		Checkout(context.Context, string) (string, error)
+		Get(context.Context, string) (*models.OrderOutput, error)
+		GetList(context.Context, string) ([]*models.OrderOutput, error)
+		Transition(context.Context, string, string, string) error
+	}
+	units interface {
+		GetList(context.Context) ([]*models.MeasureUnitOutput, error)
+		GetByName(context.Context, string) (*models.MeasureUnitOutput, error)
 	}
 	countries interface {
-		GetList() ([]*models.CountryOutput, error)
-		GetByName(string) (string, error)
+		GetList(context.Context) ([]*models.CountryOutput, error)
+		GetByName(context.Context, string) (string, error)
 	}
 }
 
 // getConnDB() - функция, устанавливающая соединение с постгрес
-func getConnDB() (*sql.DB, error) {
-	psqlInfo := fmt.Sprintf("host=%s port=%v user=%s password=%s dbname=%s sslmode=disable",
-		os.Getenv("PSQL_HOST"), os.Getenv("PSQL_PORT"), os.Getenv("PSQL_USER"), os.Getenv("PSQL_PASS"), os.Getenv("PSQL_NAME"))
-
-	db, err := sql.Open("postgres", psqlInfo)
+func getConnDB(cfg *config.DBConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN())
 	if err != nil {
 		return nil, err
 	}
@@ -53,40 +122,134 @@ func getConnDB() (*sql.DB, error) {
 	return db, nil
 }
 
-// AssembleAndGo() - собирает ядро и запускает приложение
-func AssembleAndGo() {
-	conn, err := getConnDB()
+/*
+AssembleAndGo() - собирает ядро по проверенным настройкам и запускает приложение.
+По SIGINT или SIGTERM останавливается штатно: сервер, фоновые задачи, пул соединений с БД.
+Отдает код выхода процесса.
+*/
+func AssembleAndGo(cfg *config.Config) int {
+	level, _ := logger.ParseLevel(cfg.LogLevel)
+	lg := logger.New(os.Stdout, level)
+
+	conn, err := getConnDB(&cfg.DB)
 	if err != nil {
-		panic(err)
+		lg.Error("Database connection failed", "err", err)
+		return 1
+	}
+	defer func() {
+		conn.Close()
+		lg.Info("Database connections closed")
+	}()
+
+	if cfg.AutoMigrate {
+		n, err := migrate.New(conn).Up()
+		if err != nil {
+			lg.Error("Migrations failed", "err", err)
+			return 1
+		}
+		lg.Info("Migrations applied", "count", n)
+	}
+
+	latest, err := migrate.New(conn).Latest()
+	if err != nil {
+		lg.Error("Migrations failed to load", "err", err)
+		return 1
 	}
-	defer conn.Close()
 
 	appCore := &core{
-		sign:      os.Getenv("SIGN"),
-		echo:      echo.New(),
-		infoLog:   log.New(os.Stdout, "INFO:\t", log.Ldate|log.Ltime),
-		errorLog:  log.New(os.Stdout, "ERROR:\t", log.Ldate|log.Ltime|log.Lshortfile),
-		users:     &db.UserModel{DB: conn},
-		countries: &db.CountryModel{DB: conn},
-	}
-	appCore.echo.Validator = &tools.CustomValidator{Validator: validator.New()}
+		config:        cfg,
+		echo:          echo.New(),
+		log:           lg,
+		metrics:       newAppMetrics(),
+		schemaVersion: latest,
+		health:        &db.HealthModel{DB: conn},
+		hasher:        tools.NewPasswordHasher(),
+		users:         &db.UserModel{DB: conn},
+		tokens:        &db.TokenModel{DB: conn},
+		revocations:   &db.RevocationModel{DB: conn},
+		roles:         &db.RoleModel{DB: conn},
+		shops:         &db.ShopModel{DB: conn},
+		items:         &db.ItemModel{DB: conn},
+		carts:         &db.CartModel{DB: conn},
+		orders:        &db.OrderModel{DB: conn},
+		units:         &db.UnitModel{DB: conn},
+		countries:     &db.CountryModel{DB: conn},
+	}
+	appCore.metrics.registerDBStats(conn)
+	err = appCore.configureMessages()
+	if err != nil {
+		lg.Error("Message catalog failed to load", "err", err)
+		return 1
+	}
+	// Баннер и адрес echo пишет простым текстом, вместо них в лог идет запись "Server started"
+	appCore.echo.HideBanner = true
+	appCore.echo.HidePort = true
 	appCore.configureRouting()
 
-	appCore.errorLog.Fatal(appCore.echo.Start(":8080"))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return appCore.run(ctx)
+}
+
+// configureMessages() - загружает каталог сообщений API и подключает его переводы к валидатору
+func (ac *core) configureMessages() error {
+	catalog, err := i18n.New(ac.config.Lang)
+	if err != nil {
+		return err
+	}
+
+	cv := tools.NewCustomValidator()
+	cv.Validator.RegisterCustomTypeFunc(models.NullStringValue, models.NullString{})
+	err = cv.RegisterTranslations(catalog.Translators()...)
+	if err != nil {
+		return err
+	}
+
+	ac.catalog = catalog
+	ac.echo.Validator = cv
+	return nil
 }
 
 // assembleTestCore() - собирает тестовое ядро
 func assembleTestCore() *core {
+	latest, err := migrate.New(nil).Latest()
+	if err != nil {
+		panic(err)
+	}
+
 	testCore := &core{
-		sign:      os.Getenv("TEST_SIGN"),
-		echo:      echo.New(),
-		infoLog:   log.New(ioutil.Discard, "", 0),
-		errorLog:  log.New(ioutil.Discard, "", 0),
-		users:     &mock.UserModel{},
-		countries: &mock.CountryModel{},
-	}
-	testCore.echo.Validator = &tools.CustomValidator{Validator: validator.New()}
+		config:        testConfig(),
+		echo:          echo.New(),
+		log:           logger.Discard(),
+		metrics:       newAppMetrics(),
+		schemaVersion: latest,
+		health:        &mock.HealthModel{Version: latest},
+		hasher:        &tools.PasswordHasher{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32},
+		users:         &mock.UserModel{},
+		tokens:        &mock.TokenModel{},
+		revocations:   &mock.RevocationModel{},
+		roles:         &mock.RoleModel{},
+		shops:         &mock.ShopModel{},
+		items:         &mock.ItemModel{},
+		carts:         &mock.CartModel{},
+		orders:        &mock.OrderModel{},
+		units:         &mock.UnitModel{},
+		countries:     &mock.CountryModel{},
+	}
+	if err := testCore.configureMessages(); err != nil {
+		panic(err)
+	}
 	testCore.configureRouting()
 
 	return testCore
 }
+
+// testConfig() - настройки тестового ядра, окружение процесса не читается
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Sign = "TestSign"
+	cfg.DrainGrace.Duration = 0
+
+	return cfg
+}
@@ -1,11 +1,11 @@
 package app
 
 import (
-	"crypto/sha512"
-	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 
 	"github.com/JohanVong/online_bazaar/pkg/models"
@@ -23,23 +23,27 @@ func (ac *core) signupUser(c echo.Context) error {
 		err  error
 	)
 
+	ctx := c.Request().Context()
+
 	if err = c.Bind(&user); err != nil {
-		return c.JSON(ac.bindError(err))
+		return c.JSON(ac.bindError(c, err))
 	}
 
 	if err = c.Validate(&user); err != nil {
-		return c.JSON(ac.validationError(err))
+		return c.JSON(ac.validationError(c, err))
 	}
 
-	hash64 := sha512.Sum512([]byte(user.Password))
-	hash := base64.StdEncoding.EncodeToString(hash64[:])
-	user.Password = hash
+	user.Password, err = ac.hasher.Hash(user.Password)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
 
-	err = ac.users.Insert(&user)
+	err = ac.users.Insert(ctx, &user)
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
+	ac.metrics.signups.Inc()
 	return c.JSON(ac.respondOK("OK"))
 }
 
@@ -52,71 +56,254 @@ func (ac *core) loginUser(c echo.Context) error {
 		err  error
 	)
 
+	ctx := c.Request().Context()
+
 	if err = c.Bind(&uli); err != nil {
-		return c.JSON(ac.bindError(err))
+		return c.JSON(ac.bindError(c, err))
 	}
 
-	uodb, err = ac.users.Get(uli.Username, false)
+	uodb, err = ac.users.Get(ctx, uli.Username, false)
 	if err != nil {
-		return c.JSON(ac.unauthorized("Wrong credentials provided"))
+		if errors.Is(err, models.ErrUserNotFound) {
+			// Пароль все равно сверяется, чтобы по времени ответа нельзя было понять, есть ли такой пользователь
+			ac.hasher.Verify(uli.Password, ac.missingUserHash())
+			ac.metrics.loginFailures.Inc()
+			err = models.ErrBadCredentials
+		}
+		return c.JSON(ac.respondError(c, err))
 	}
 
-	hash64 := sha512.Sum512([]byte(uli.Password))
-	hash := base64.StdEncoding.EncodeToString(hash64[:])
-	if hash != uodb.Hash {
-		return c.JSON(ac.unauthorized("Wrong credentials provided"))
+	match, rehash, err := ac.hasher.Verify(uli.Password, uodb.Hash)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+	if !match {
+		ac.metrics.loginFailures.Inc()
+		return c.JSON(ac.respondError(c, models.ErrBadCredentials))
 	}
 
 	if !uodb.DeletedAt.IsZero() {
-		return c.JSON(ac.unauthorized("User was deleted"))
+		return c.JSON(ac.respondError(c, models.ErrUserDeleted))
 	}
 
-	claims := jwt.MapClaims{}
-	claims["UID"] = uodb.UserUID
-	claims["exp"] = time.Now().Add(time.Minute * 3).Unix()
+	// Хэш в устаревшем формате пересчитываем, пока у нас на руках открытый пароль.
+	// Неудача здесь не должна мешать входу, поэтому только логируем ее
+	if rehash {
+		ac.rehashPassword(ctx, uodb.UserUID, uli.Password)
+	}
+
+	ulo.Token, err = ac.accessToken(uodb.UserUID, uodb.Roles)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
 
-	// Create token with claims & generate encoded one
-	token, err := ac.generateToken(claims, true)
+	err = ac.tokens.Insert(ctx, uodb.UserUID, "", hash, time.Now().Add(refreshTokenTTL))
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
-	ulo.Token = token
+	ac.metrics.logins.Inc()
+	ulo.RefreshToken = refresh
 	return c.JSON(ac.respondOK(ulo))
 }
 
-// updateUser() - хэндлер для обновления данных пользователя
-func (ac *core) updateUser(c echo.Context) error {
+/*
+refreshToken() - хэндлер для обмена refresh-токена на новую пару токенов.
+Каждый refresh-токен одноразовый: повторное предъявление уже обмененного токена
+считается утечкой, и вся цепочка токенов этого логина отзывается.
+*/
+func (ac *core) refreshToken(c echo.Context) error {
+	var (
+		rti models.RefreshTokenInput
+		ulo models.UserLoginOutput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	if err = c.Bind(&rti); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&rti); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	rt, err := ac.tokens.Get(ctx, hashRefreshToken(rti.RefreshToken))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !rt.RevokedAt.IsZero() {
+		return c.JSON(ac.respondError(c, models.ErrBadRefreshToken))
+	}
+
+	if !rt.UsedAt.IsZero() {
+		return c.JSON(ac.tokenReused(c, rt.FamilyUID))
+	}
+
+	if time.Now().After(rt.ExpiresAt) {
+		return c.JSON(ac.respondError(c, models.ErrRefreshTokenExpired))
+	}
+
+	uodb, err := ac.users.Get(ctx, rt.UserUID, true)
+	if err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			err = models.ErrBadRefreshToken
+		}
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !uodb.DeletedAt.IsZero() {
+		return c.JSON(ac.respondError(c, models.ErrUserDeleted))
+	}
+
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	err = ac.tokens.Rotate(ctx, rt, hash, time.Now().Add(refreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, models.ErrTokenReused) {
+			return c.JSON(ac.tokenReused(c, rt.FamilyUID))
+		}
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	ulo.Token, err = ac.accessToken(uodb.UserUID, uodb.Roles)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	ulo.RefreshToken = refresh
+	return c.JSON(ac.respondOK(ulo))
+}
+
+// logoutUser() - хэндлер для выхода: отзывает текущий токен доступа и, если передан, refresh-токен
+func (ac *core) logoutUser(c echo.Context) error {
 	var (
-		uus models.UserUpdateInput
+		li  models.LogoutInput
 		err error
 	)
 
+	ctx := c.Request().Context()
 	uid := c.Get("uid").(string)
+	jti := c.Get("jti").(string)
+	exp := c.Get("exp").(time.Time)
 
-	if err = c.Bind(&uus); err != nil {
-		return c.JSON(ac.bindError(err))
+	if err = c.Bind(&li); err != nil {
+		return c.JSON(ac.bindError(c, err))
 	}
 
-	if uus.Email != "" {
-		err = c.Validate(&uus)
-		if err != nil {
-			return c.JSON(ac.validationError(err))
+	err = ac.revocations.Revoke(ctx, jti, exp)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if li.RefreshToken != "" {
+		rt, err := ac.tokens.Get(ctx, hashRefreshToken(li.RefreshToken))
+		if err == nil && rt.UserUID == uid {
+			err = ac.tokens.RevokeFamily(ctx, rt.FamilyUID)
+			if err != nil {
+				return c.JSON(ac.respondError(c, err))
+			}
 		}
 	}
 
-	if uus.Country != "" {
-		cuid, err := ac.countries.GetByName(uus.Country)
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// logoutUserAll() - хэндлер для выхода со всех устройств: отзывает все токены пользователя
+func (ac *core) logoutUserAll(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	// Время отзыва хранится с той же точностью, что и iat в токенах, - до микросекунды
+	err := ac.revocations.RevokeAll(ctx, uid, time.Now().Truncate(time.Microsecond))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	err = ac.tokens.RevokeUser(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// getMe() - хэндлер для профиля текущего пользователя, с ETag для кэширования и условных изменений
+func (ac *core) getMe(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	uo, err := ac.users.Get(ctx, uid, true)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return ac.respondETag(c, uo.Profile(), models.Version(uo.CreatedAt, uo.UpdatedAt))
+}
+
+// updateUser() - хэндлер для обновления данных пользователя, пустые строки в нем значат "не менять"
+func (ac *core) updateUser(c echo.Context) error {
+	var uus models.UserUpdateInput
+
+	if err := c.Bind(&uus); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	uus.OmitEmpty()
+	return ac.applyUserUpdate(c, &uus)
+}
+
+/*
+patchUser() - хэндлер для частичного обновления данных пользователя по RFC 7396 (JSON Merge Patch):
+поля, которых нет в теле, не меняются, null очищает поле. Тело принимается
+как application/merge-patch+json, так и как application/json.
+*/
+func (ac *core) patchUser(c echo.Context) error {
+	var uus models.UserUpdateInput
+
+	ctype := c.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(ctype, mimeMergePatch) && !strings.HasPrefix(ctype, echo.MIMEApplicationJSON) {
+		return c.JSON(ac.bindError(c, echo.ErrUnsupportedMediaType))
+	}
+
+	if err := json.NewDecoder(c.Request().Body).Decode(&uus); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	return ac.applyUserUpdate(c, &uus)
+}
+
+// applyUserUpdate() - общая часть updateUser и patchUser: валидация пришедших полей, поиск страны и запись
+func (ac *core) applyUserUpdate(c echo.Context, uus *models.UserUpdateInput) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	if err := c.Validate(uus); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	if uus.Country.Set {
+		cuid, err := ac.countries.GetByName(ctx, uus.Country.String)
 		if err != nil {
-			return c.JSON(ac.serverError(err))
+			return c.JSON(ac.respondError(c, err))
 		}
 
-		uus.Country = cuid
+		uus.Country.String = cuid
 	}
 
-	err = ac.users.Update(uid, &uus)
+	err := ac.users.Update(ctx, uid, uus)
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
 	return c.JSON(ac.respondOK("OK"))
@@ -129,23 +316,25 @@ func (ac *core) updateUserPassword(c echo.Context) error {
 		err error
 	)
 
+	ctx := c.Request().Context()
 	uid := c.Get("uid").(string)
 
 	if err = c.Bind(&upi); err != nil {
-		return c.JSON(ac.bindError(err))
+		return c.JSON(ac.bindError(c, err))
 	}
 
 	if err = c.Validate(&upi); err != nil {
-		return c.JSON(ac.validationError(err))
+		return c.JSON(ac.validationError(c, err))
 	}
 
-	hash64 := sha512.Sum512([]byte(upi.NewPassword))
-	hash := base64.StdEncoding.EncodeToString(hash64[:])
-	upi.NewPassword = hash
+	upi.NewPassword, err = ac.hasher.Hash(upi.NewPassword)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
 
-	err = ac.users.UpdatePassword(uid, &upi)
+	err = ac.users.UpdatePassword(ctx, uid, &upi)
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
 	return c.JSON(ac.respondOK("OK"))
@@ -154,21 +343,620 @@ func (ac *core) updateUserPassword(c echo.Context) error {
 // deleteUser() - хэндлер для "удаления" пользователя.
 // Важно: Пользователь не будет удален, но будет деактивирован
 func (ac *core) deleteUser(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	err := ac.users.Delete(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// grantRole() - хэндлер для выдачи роли пользователю администратором
+func (ac *core) grantRole(c echo.Context) error {
+	var (
+		ri  models.RoleInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	if err = c.Bind(&ri); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&ri); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	err = ac.roles.Grant(ctx, ri.UserUID, ri.Role)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// revokeRole() - хэндлер для отзыва роли у пользователя администратором
+func (ac *core) revokeRole(c echo.Context) error {
+	var (
+		ri  models.RoleInput
+		err error
+	)
+
+	ctx := c.Request().Context()
 	uid := c.Get("uid").(string)
 
-	err := ac.users.Delete(uid)
+	if err = c.Bind(&ri); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&ri); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	// Иначе можно остаться вовсе без администраторов
+	if ri.UserUID == uid && ri.Role == models.RoleAdmin {
+		return c.JSON(ac.respondError(c, models.ErrOwnAdminRole))
+	}
+
+	err = ac.roles.Revoke(ctx, ri.UserUID, ri.Role)
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
 	return c.JSON(ac.respondOK("OK"))
 }
 
+// createShop() - хэндлер для создания магазина текущим пользователем
+func (ac *core) createShop(c echo.Context) error {
+	var (
+		si  models.ShopInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	if err = c.Bind(&si); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&si); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	suid, err := ac.shops.Insert(ctx, uid, &si)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(suid))
+}
+
+// getShop() - хэндлер для получения данных о магазине
+func (ac *core) getShop(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	so, err := ac.shops.Get(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !so.DeletedAt.IsZero() {
+		return c.JSON(ac.respondError(c, models.ErrShopDeleted))
+	}
+
+	return ac.respondETag(c, so, models.Version(so.CreatedAt, so.UpdatedAt))
+}
+
+// getShops() - хэндлер для получения списка действующих магазинов
+func (ac *core) getShops(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	so, err := ac.shops.GetList(ctx, "")
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(so))
+}
+
+// getMyShops() - хэндлер для получения списка магазинов текущего пользователя
+func (ac *core) getMyShops(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	so, err := ac.shops.GetList(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(so))
+}
+
+// updateShop() - хэндлер для обновления данных магазина его владельцем
+func (ac *core) updateShop(c echo.Context) error {
+	var (
+		sui models.ShopUpdateInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	so, code, resp := ac.ownShop(c, c.Param("uid"))
+	if so == nil {
+		return c.JSON(code, resp)
+	}
+
+	if err = c.Bind(&sui); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&sui); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	err = ac.shops.Update(ctx, so.ShopUID, &sui)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// deleteShop() - хэндлер для "удаления" магазина его владельцем.
+// Как и с пользователем, запись остается в БД, заполняется только deleted_at в истории
+func (ac *core) deleteShop(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	so, code, resp := ac.ownShop(c, c.Param("uid"))
+	if so == nil {
+		return c.JSON(code, resp)
+	}
+
+	err := ac.shops.Delete(ctx, so.ShopUID)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// createItem() - хэндлер для создания товара в магазине текущего пользователя
+func (ac *core) createItem(c echo.Context) error {
+	var (
+		ii  models.ItemInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	if err = c.Bind(&ii); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&ii); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	so, code, resp := ac.ownShop(c, ii.ShopUID)
+	if so == nil {
+		return c.JSON(code, resp)
+	}
+
+	if ii.MeasureUnit == "" {
+		ii.MeasureUnit = models.DefaultMeasureUnit
+	}
+
+	mu, err := ac.units.GetByName(ctx, ii.MeasureUnit)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	// Остаток штучного товара - целое число единиц
+	if !mu.Fractional && !ii.InStock.IsWhole() {
+		return c.JSON(ac.respondError(c, models.ErrFractionalQuantity))
+	}
+
+	iuid, err := ac.items.Insert(ctx, &ii)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(iuid))
+}
+
+// getItem() - хэндлер для получения данных о товаре
+func (ac *core) getItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	io, err := ac.items.Get(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !io.DeletedAt.IsZero() {
+		return c.JSON(ac.respondError(c, models.ErrItemDeleted))
+	}
+
+	return ac.respondETag(c, io, models.Version(io.CreatedAt, io.UpdatedAt))
+}
+
+// searchItems() - хэндлер для поиска товаров с фильтрами, сортировкой и постраничной выдачей
+func (ac *core) searchItems(c echo.Context) error {
+	var (
+		isi models.ItemSearchInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	if err = c.Bind(&isi); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&isi); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	if isi.PriceMax > 0 && isi.PriceMin > isi.PriceMax {
+		return c.JSON(ac.respondError(c, models.ErrBadPriceRange))
+	}
+
+	iso, err := ac.items.Search(ctx, &isi)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(iso))
+}
+
+// getShopItems() - хэндлер для получения списка товаров магазина
+func (ac *core) getShopItems(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	io, err := ac.items.GetList(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(io))
+}
+
+// updateItem() - хэндлер для обновления данных товара владельцем магазина
+func (ac *core) updateItem(c echo.Context) error {
+	var (
+		iui models.ItemUpdateInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	io, code, resp := ac.ownItem(c, c.Param("uid"))
+	if io == nil {
+		return c.JSON(code, resp)
+	}
+
+	if err = c.Bind(&iui); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&iui); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	err = ac.items.Update(ctx, io.ItemUID, &iui)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// restockItem() - хэндлер для пополнения или списания остатка товара
+func (ac *core) restockItem(c echo.Context) error {
+	var (
+		iri models.ItemRestockInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+
+	io, code, resp := ac.ownItem(c, c.Param("uid"))
+	if io == nil {
+		return c.JSON(code, resp)
+	}
+
+	if err = c.Bind(&iri); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&iri); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	mu, err := ac.units.GetByName(ctx, io.MeasureUnit)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !mu.Fractional && !iri.Delta.IsWhole() {
+		return c.JSON(ac.respondError(c, models.ErrFractionalQuantity))
+	}
+
+	err = ac.items.Restock(ctx, io.ItemUID, iri.Delta)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// deleteItem() - хэндлер для "удаления" товара владельцем магазина
+func (ac *core) deleteItem(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	io, code, resp := ac.ownItem(c, c.Param("uid"))
+	if io == nil {
+		return c.JSON(code, resp)
+	}
+
+	err := ac.items.Delete(ctx, io.ItemUID)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// getCart() - хэндлер для получения корзины текущего пользователя
+func (ac *core) getCart(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	co, err := ac.carts.Get(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(co))
+}
+
+/*
+addToCart() - хэндлер для добавления товара в корзину. Если товар уже там, количество суммируется:
+проверки строки проходит сумма, а в БД она прибавляется одним запросом, чтобы параллельные
+добавления не потеряли друг друга
+*/
+func (ac *core) addToCart(c echo.Context) error {
+	var (
+		cii models.CartItemInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	if err = c.Bind(&cii); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&cii); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	co, err := ac.carts.Get(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	total := cii.Quantity
+	for _, l := range co.Lines {
+		if l.ItemUID == cii.ItemUID {
+			total, err = total.Add(l.Quantity)
+			if err != nil {
+				return c.JSON(ac.respondError(c, err))
+			}
+		}
+	}
+
+	if total > models.MaxQuantity {
+		return c.JSON(ac.respondError(c, models.ErrDecimalOverflow))
+	}
+
+	muid, code, resp := ac.checkCartLine(c, cii.ItemUID, total, cii.MeasureUnit)
+	if muid == "" {
+		return c.JSON(code, resp)
+	}
+
+	err = ac.carts.Add(ctx, uid, cii.ItemUID, cii.Quantity, muid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return ac.getCart(c)
+}
+
+// updateCartItem() - хэндлер для изменения количества товара в корзине
+func (ac *core) updateCartItem(c echo.Context) error {
+	var (
+		cii models.CartItemInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	if err = c.Bind(&cii); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&cii); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	muid, code, resp := ac.checkCartLine(c, cii.ItemUID, cii.Quantity, cii.MeasureUnit)
+	if muid == "" {
+		return c.JSON(code, resp)
+	}
+
+	err = ac.carts.Put(ctx, uid, cii.ItemUID, cii.Quantity, muid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return ac.getCart(c)
+}
+
+// removeFromCart() - хэндлер для удаления товара из корзины
+func (ac *core) removeFromCart(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	err := ac.carts.Remove(ctx, uid, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return ac.getCart(c)
+}
+
+// clearCart() - хэндлер для очистки корзины
+func (ac *core) clearCart(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	err := ac.carts.Clear(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// checkout() - хэндлер для оформления заказа из корзины текущего пользователя
+func (ac *core) checkout(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	ouid, err := ac.orders.Checkout(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	ac.metrics.ordersCreated.Inc()
+	return c.JSON(ac.respondOK(ouid))
+}
+
+// getOrders() - хэндлер для получения списка заказов текущего пользователя
+func (ac *core) getOrders(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	oo, err := ac.orders.GetList(ctx, uid)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(oo))
+}
+
+// getOrder() - хэндлер для получения заказа покупателем или продавцом одного из его товаров
+func (ac *core) getOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	oo, err := ac.orders.Get(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !isOwner(c, oo.UserUID) && !sellsInOrder(c, oo) {
+		return c.JSON(ac.respondError(c, models.ErrAccessDenied))
+	}
+
+	return ac.respondETag(c, oo, models.Version(oo.CreatedAt, oo.UpdatedAt))
+}
+
+// cancelOrder() - хэндлер для отмены заказа покупателем
+func (ac *core) cancelOrder(c echo.Context) error {
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	oo, err := ac.orders.Get(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !isOwner(c, oo.UserUID) {
+		return c.JSON(ac.respondError(c, models.ErrAccessDenied))
+	}
+
+	return ac.transitOrder(c, oo.OrderUID, models.StatusCancelled, uid)
+}
+
+// advanceOrder() - хэндлер для смены статуса заказа продавцом всех его товаров
+func (ac *core) advanceOrder(c echo.Context) error {
+	var (
+		osi models.OrderStatusInput
+		err error
+	)
+
+	ctx := c.Request().Context()
+	uid := c.Get("uid").(string)
+
+	oo, err := ac.orders.Get(ctx, c.Param("uid"))
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	if !sellsWholeOrder(c, oo) {
+		return c.JSON(ac.respondError(c, models.ErrAccessDenied))
+	}
+
+	if err = c.Bind(&osi); err != nil {
+		return c.JSON(ac.bindError(c, err))
+	}
+
+	if err = c.Validate(&osi); err != nil {
+		return c.JSON(ac.validationError(c, err))
+	}
+
+	return ac.transitOrder(c, oo.OrderUID, osi.Status, uid)
+}
+
+// transitOrder() - общая часть смены статуса заказа
+func (ac *core) transitOrder(c echo.Context, ouid, to, actorUID string) error {
+	ctx := c.Request().Context()
+
+	err := ac.orders.Transition(ctx, ouid, to, actorUID)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK("OK"))
+}
+
+// getUnits - хэндлер для получения списка единиц измерения
+func (ac *core) getUnits(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	mu, err := ac.units.GetList(ctx)
+	if err != nil {
+		return c.JSON(ac.respondError(c, err))
+	}
+
+	return c.JSON(ac.respondOK(mu))
+}
+
 // getCountries - хэндлер для получения списка стран из БД
 func (ac *core) getCountries(c echo.Context) error {
-	co, err := ac.countries.GetList()
+	ctx := c.Request().Context()
+
+	co, err := ac.countries.GetList(ctx)
 	if err != nil {
-		return c.JSON(ac.serverError(err))
+		return c.JSON(ac.respondError(c, err))
 	}
 
 	return c.JSON(ac.respondOK(co))
@@ -1,13 +1,21 @@
 package app
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+	"github.com/JohanVong/online_bazaar/pkg/models/mock"
+	"github.com/JohanVong/online_bazaar/tools"
 )
 
 func TestPing(t *testing.T) {
@@ -52,7 +60,7 @@ func TestSignupUser(t *testing.T) {
 				"Country":"TestCountry",
 			}`,
 			400,
-			`{"Error":"Wrong data format"}`,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
 		},
 		{ // username validation error
 			`{
@@ -63,7 +71,7 @@ func TestSignupUser(t *testing.T) {
 				"Country":"TestCountry"
 			}`,
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Username","Rule":"required","Message":"Username is required"}]}`,
 		},
 		{ // password validation error
 			`{
@@ -74,7 +82,7 @@ func TestSignupUser(t *testing.T) {
 				"Country":"TestCountry"
 			}`,
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Password","Rule":"min","Message":"Password must be at least 8 characters long"}]}`,
 		},
 		{ // email validation error
 			`{
@@ -85,7 +93,7 @@ func TestSignupUser(t *testing.T) {
 				"Country":"TestCountry"
 			}`,
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Email","Rule":"email","Message":"Email must be a valid email address"}]}`,
 		},
 		{ // unique constraint violation
 			`{
@@ -95,8 +103,8 @@ func TestSignupUser(t *testing.T) {
 				"Phone":"87776665544",
 				"Country":"TestCountry"
 			}`,
-			500,
-			`{"Error":"duplicate key value violates unique constraint"}`,
+			409,
+			`{"Error":"Username or email is already taken","Code":"user_exists"}`,
 		},
 		{ // non-existing country
 			`{
@@ -106,8 +114,8 @@ func TestSignupUser(t *testing.T) {
 				"Phone":"87776665544",
 				"Country":"No existing country"
 			}`,
-			500,
-			`{"Error":"Provided country does not exist"}`,
+			400,
+			`{"Error":"Provided country does not exist","Code":"unknown_country"}`,
 		},
 	}
 
@@ -147,7 +155,7 @@ func TestLoginUser(t *testing.T) {
 				"Password": "TestPassword",
 			}`,
 			400,
-			`{"Error":"Wrong data format"}`,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
 		},
 		{ // wrong username
 			`{
@@ -155,7 +163,7 @@ func TestLoginUser(t *testing.T) {
 				"Password": "TestPassword"
 			}`,
 			401,
-			`{"Error":"Wrong credentials provided"}`,
+			`{"Error":"Wrong credentials provided","Code":"wrong_credentials"}`,
 		},
 		{ // wrong password
 			`{
@@ -163,7 +171,7 @@ func TestLoginUser(t *testing.T) {
 				"Password": "Test"
 			}`,
 			401,
-			`{"Error":"Wrong credentials provided"}`,
+			`{"Error":"Wrong credentials provided","Code":"wrong_credentials"}`,
 		},
 		{ // deleted user
 			`{
@@ -171,7 +179,7 @@ func TestLoginUser(t *testing.T) {
 				"Password": "TestPassword"
 			}`,
 			401,
-			`{"Error":"User was deleted"}`,
+			`{"Error":"User was deleted","Code":"user_deleted"}`,
 		},
 	}
 
@@ -192,6 +200,55 @@ func TestLoginUser(t *testing.T) {
 	}
 }
 
+// spyHasher - хэшер, который запоминает, с какими хэшами сверялись пароли
+type spyHasher struct {
+	*tools.PasswordHasher
+	verified []string
+}
+
+func (sh *spyHasher) Verify(password, encoded string) (bool, bool, error) {
+	sh.verified = append(sh.verified, encoded)
+	return sh.PasswordHasher.Verify(password, encoded)
+}
+
+func TestLoginHashing(t *testing.T) {
+	testCore := assembleTestCore()
+	hasher := &spyHasher{PasswordHasher: testCore.hasher.(*tools.PasswordHasher)}
+	users := &mock.UserModel{Rehashed: map[string]string{}}
+	testCore.hasher = hasher
+	testCore.users = users
+
+	login := func(username, password string) int {
+		body := fmt.Sprintf(`{"Username":%q,"Password":%q}`, username, password)
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+
+		assert.NoError(t, testCore.loginUser(testCore.echo.NewContext(req, rec)))
+		return rec.Code
+	}
+
+	// missing user, password is still checked against a hash of the same kind
+	assert.Equal(t, http.StatusUnauthorized, login("Missing", "TestPassword"))
+	if assert.Len(t, hasher.verified, 1) {
+		assert.True(t, strings.HasPrefix(hasher.verified[0], "$argon2id$"), hasher.verified[0])
+	}
+
+	// wrong password for a legacy hash, nothing to rehash
+	assert.Equal(t, http.StatusUnauthorized, login("TestUser", "WrongPassword"))
+	assert.Empty(t, users.Rehashed)
+
+	// right password for a legacy hash, it is replaced with argon2id of the same password
+	assert.Equal(t, http.StatusOK, login("TestUser", "TestPassword"))
+	rehashed, ok := users.Rehashed["uuid.v6[1]"]
+	if assert.True(t, ok, "legacy hash is rehashed") {
+		match, rehash, err := hasher.PasswordHasher.Verify("TestPassword", rehashed)
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.False(t, rehash, "new hash uses current parameters")
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	testCore := assembleTestCore()
 
@@ -216,7 +273,7 @@ func TestUpdateUser(t *testing.T) {
 				"Country": "TestCountry2",
 			}`,
 			400,
-			`{"Error":"Wrong data format"}`,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
 		},
 		{ // validation error, wrong format email
 			`{
@@ -225,7 +282,7 @@ func TestUpdateUser(t *testing.T) {
 				"Country": ""
 			}`,
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Email","Rule":"email","Message":"Email must be a valid email address"}]}`,
 		},
 		{ // non-existing country
 			`{
@@ -233,8 +290,8 @@ func TestUpdateUser(t *testing.T) {
 				"Phone": "",
 				"Country": "Non-Existing Country"
 			}`,
-			500,
-			`{"Error":"Provided country does not exist"}`,
+			400,
+			`{"Error":"Provided country does not exist","Code":"unknown_country"}`,
 		},
 		{ // nothing to update
 			`{
@@ -242,8 +299,8 @@ func TestUpdateUser(t *testing.T) {
 				"Phone": "",
 				"Country": ""
 			}`,
-			500,
-			`{"Error":"Nothing to update"}`,
+			400,
+			`{"Error":"Nothing to update","Code":"nothing_to_update"}`,
 		},
 	}
 
@@ -261,6 +318,85 @@ func TestUpdateUser(t *testing.T) {
 	}
 }
 
+func TestPatchUser(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		input    string
+		ctype    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // phone is cleared, other fields are untouched
+			`{"Phone": null}`,
+			"application/merge-patch+json",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // plain json is accepted as well
+			`{"Email": "new@mail.test", "Country": "TestCountry2"}`,
+			"application/json",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // email can not be cleared
+			`{"Email": null}`,
+			"application/merge-patch+json",
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Email","Rule":"required","Message":"Email is required"}]}`,
+		},
+		{ // empty email is not "not provided" anymore
+			`{"Email": "", "Phone": "+77001234567"}`,
+			"application/merge-patch+json",
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Email","Rule":"required","Message":"Email is required"}]}`,
+		},
+		{ // country can not be cleared
+			`{"Country": null}`,
+			"application/merge-patch+json",
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Country","Rule":"required","Message":"Country is required"}]}`,
+		},
+		{ // email is taken by someone else
+			`{"Email": "taken@mail.test"}`,
+			"application/merge-patch+json",
+			409,
+			`{"Error":"Username or email is already taken","Code":"user_exists"}`,
+		},
+		{ // empty patch
+			`{}`,
+			"application/merge-patch+json",
+			400,
+			`{"Error":"Nothing to update","Code":"nothing_to_update"}`,
+		},
+		{ // patch is not an object
+			`["Phone"]`,
+			"application/merge-patch+json",
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // unsupported content type
+			`Phone=`,
+			"application/x-www-form-urlencoded",
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", tt.ctype)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[1]")
+
+		if assert.NoError(t, testCore.patchUser(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
 func TestUpdateUserPassword(t *testing.T) {
 	testCore := assembleTestCore()
 
@@ -284,7 +420,7 @@ func TestUpdateUserPassword(t *testing.T) {
 			}`,
 			"uuid.v6[1]",
 			400,
-			`{"Error":"Wrong data format"}`,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
 		},
 		{ // validation error (required)
 			`{
@@ -292,7 +428,7 @@ func TestUpdateUserPassword(t *testing.T) {
 			}`,
 			"uuid.v6[1]",
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"NewPassword","Rule":"required","Message":"NewPassword is required"}]}`,
 		},
 		{ // validation error (min len)
 			`{
@@ -300,15 +436,15 @@ func TestUpdateUserPassword(t *testing.T) {
 			}`,
 			"uuid.v6[1]",
 			400,
-			`{"Error":"Data validation failed"}`,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"NewPassword","Rule":"required","Message":"NewPassword is required"}]}`,
 		},
 		{ // non-existing user
 			`{
 				"NewPassword": "12345678"
 			}`,
 			"uuid.v6[93]",
-			500,
-			`{"Error":"No record found"}`,
+			404,
+			`{"Error":"User not found","Code":"user_not_found"}`,
 		},
 	}
 
@@ -341,8 +477,8 @@ func TestDeleteUser(t *testing.T) {
 		},
 		{ // no such user
 			"uuid.v6[2]",
-			500,
-			`{"Error":"No record found"}`,
+			404,
+			`{"Error":"User not found","Code":"user_not_found"}`,
 		},
 	}
 
@@ -361,6 +497,97 @@ func TestDeleteUser(t *testing.T) {
 	}
 }
 
+func TestGetMe(t *testing.T) {
+	testCore := assembleTestCore()
+
+	get := func(uid, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("", "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", uid)
+
+		assert.NoError(t, testCore.getMe(c))
+		return rec
+	}
+
+	rec := get("uuid.v6[6]", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+	var resp struct {
+		Data map[string]interface{}
+	}
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp)) {
+		assert.Equal(t, "uuid.v6[6]", resp.Data["UserUID"])
+		assert.Equal(t, "TestAdmin", resp.Data["Username"])
+		assert.Equal(t, "testadmin@mail.test", resp.Data["Email"])
+		assert.Equal(t, "87770001122", resp.Data["Phone"])
+		assert.Equal(t, "TestCountry", resp.Data["Country"])
+		assert.Equal(t, []interface{}{"admin", "buyer"}, resp.Data["Roles"])
+		assert.Contains(t, resp.Data, "CreatedAt")
+		assert.Contains(t, resp.Data, "UpdatedAt")
+		for _, hidden := range []string{"Hash", "HistoryUID", "CountryUID", "DeletedAt"} {
+			assert.NotContains(t, resp.Data, hidden)
+		}
+	}
+
+	admin, err := testCore.users.Get(context.Background(), "uuid.v6[6]", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := rec.Header().Get("ETag")
+	assert.Equal(t, `"`+models.Version(admin.CreatedAt, admin.UpdatedAt)+`"`, tag, "ETag is the version of the record")
+	assert.Equal(t, tag, get("uuid.v6[6]", "").Header().Get("ETag"), "same record, same ETag")
+
+	tests := []struct {
+		uid         string
+		ifNoneMatch string
+		wantCode    int
+		wantBody    string
+	}{
+		{ // cached copy is current
+			"uuid.v6[6]",
+			tag,
+			http.StatusNotModified,
+			"",
+		},
+		{ // one of several cached copies is current, weak comparison
+			"uuid.v6[6]",
+			`"stale", W/` + tag,
+			http.StatusNotModified,
+			"",
+		},
+		{ // any cached copy is current
+			"uuid.v6[6]",
+			"*",
+			http.StatusNotModified,
+			"",
+		},
+		{ // cached copy is stale
+			"uuid.v6[6]",
+			`"stale"`,
+			http.StatusOK,
+			strings.TrimSpace(rec.Body.String()),
+		},
+		{ // no such user
+			"uuid.v6[2]",
+			"",
+			http.StatusNotFound,
+			`{"Error":"User not found","Code":"user_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		rec := get(tt.uid, tt.ifNoneMatch)
+		assert.Equal(t, tt.wantCode, rec.Code, tt.ifNoneMatch)
+		assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()), tt.ifNoneMatch)
+	}
+}
+
 func TestGetCountries(t *testing.T) {
 	wantBody := `{"Data":[{"Name":"TestCountry"},{"Name":"TestCountry2"},{"Name":"TestCountry3"}]}`
 
@@ -374,3 +601,1254 @@ func TestGetCountries(t *testing.T) {
 		assert.Equal(t, strings.TrimSpace(wantBody), strings.TrimSpace(rec.Body.String()))
 	}
 }
+
+func TestRefreshToken(t *testing.T) {
+	testCore := assembleTestCore()
+
+	call := func(h echo.HandlerFunc, input string) (int, models.UserLoginOutput, string) {
+		var resp struct {
+			Data models.UserLoginOutput
+		}
+
+		req := httptest.NewRequest("", "/", strings.NewReader(input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+
+		if !assert.NoError(t, h(c)) {
+			t.FailNow()
+		}
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+
+		return rec.Code, resp.Data, strings.TrimSpace(rec.Body.String())
+	}
+	refresh := func(token string) (int, models.UserLoginOutput, string) {
+		return call(testCore.refreshToken, fmt.Sprintf(`{"RefreshToken":"%s"}`, token))
+	}
+
+	code, login, _ := call(testCore.loginUser, `{"Username":"TestUser","Password":"TestPassword"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, login.RefreshToken)
+
+	// good request, token is rotated
+	code, first, _ := refresh(login.RefreshToken)
+	assert.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, first.Token)
+	assert.NotEqual(t, login.RefreshToken, first.RefreshToken)
+
+	// validation error
+	code, _, body := call(testCore.refreshToken, `{"RefreshToken":""}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, `{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"RefreshToken","Rule":"required","Message":"RefreshToken is required"}]}`, body)
+
+	// unknown token
+	code, _, body = refresh("unknown")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, `{"Error":"Invalid refresh token","Code":"invalid_refresh_token"}`, body)
+
+	// replay of already rotated token revokes the whole family
+	code, _, body = refresh(login.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, `{"Error":"Refresh token reuse detected","Code":"token_reused"}`, body)
+
+	code, _, body = refresh(first.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	assert.Equal(t, `{"Error":"Invalid refresh token","Code":"invalid_refresh_token"}`, body)
+}
+
+func TestLogoutUser(t *testing.T) {
+	testCore := assembleTestCore()
+	exp := time.Now().Add(time.Minute * 3)
+
+	tests := []struct {
+		input    string
+		jti      string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			`{}`,
+			"jti[1]",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // good request with refresh token
+			`{"RefreshToken":"unknown"}`,
+			"jti[2]",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // wrong json
+			`{"RefreshToken":"unknown",}`,
+			"jti[3]",
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("jti", tt.jti)
+		c.Set("exp", exp)
+
+		if assert.NoError(t, testCore.logoutUser(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+
+			revoked, err := testCore.revocations.IsRevoked(context.Background(), tt.jti, "uuid.v6[1]", time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode == 200, revoked)
+		}
+	}
+}
+
+func TestLogoutUserAll(t *testing.T) {
+	testCore := assembleTestCore()
+	issuedAt := time.Now().Add(-time.Minute)
+
+	req := httptest.NewRequest("", "/", nil)
+	rec := httptest.NewRecorder()
+	c := testCore.echo.NewContext(req, rec)
+	c.Set("uid", "uuid.v6[1]")
+
+	if assert.NoError(t, testCore.logoutUserAll(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"Data":"OK"}`, strings.TrimSpace(rec.Body.String()))
+
+		revoked, err := testCore.revocations.IsRevoked(context.Background(), "jti[any]", "uuid.v6[1]", issuedAt)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		revoked, err = testCore.revocations.IsRevoked(context.Background(), "jti[any]", "uuid.v6[1]", time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	}
+}
+
+func TestGrantRole(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			`{"UserUID":"uuid.v6[1]","Role":"seller"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // wrong json
+			`{"UserUID":"uuid.v6[1]","Role":"seller",}`,
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // unknown role
+			`{"UserUID":"uuid.v6[1]","Role":"owner"}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Role","Rule":"oneof","Message":"Role must be one of: buyer, seller, admin"}]}`,
+		},
+		{ // non-existing user
+			`{"UserUID":"uuid.v6[93]","Role":"seller"}`,
+			400,
+			`{"Error":"Referenced record does not exist","Code":"bad_reference"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[6]")
+
+		if assert.NoError(t, testCore.grantRole(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestRevokeRole(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			`{"UserUID":"uuid.v6[1]","Role":"seller"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // validation error
+			`{"UserUID":"","Role":"seller"}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"UserUID","Rule":"required","Message":"UserUID is required"}]}`,
+		},
+		{ // admin revokes own admin role
+			`{"UserUID":"uuid.v6[6]","Role":"admin"}`,
+			403,
+			`{"Error":"Admin can not revoke own admin role","Code":"own_admin_role"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[6]")
+
+		if assert.NoError(t, testCore.revokeRole(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestCreateShop(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			`{"Name":"NewShop","Description":"Brand new shop"}`,
+			200,
+			`{"Data":"shop[4]"}`,
+		},
+		{ // wrong json
+			`{"Name":"NewShop",}`,
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // name validation error
+			`{"Name":"","Description":"Brand new shop"}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Name","Rule":"required","Message":"Name is required"}]}`,
+		},
+		{ // unique constraint violation
+			`{"Name":"Exists"}`,
+			409,
+			`{"Error":"Shop name is already taken","Code":"shop_exists"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[1]")
+
+		if assert.NoError(t, testCore.createShop(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestGetShop(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		suid     string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"shop[1]",
+			200,
+			`{"Data":{"ShopUID":"shop[1]"`,
+		},
+		{ // deleted shop
+			"shop[3]",
+			404,
+			`{"Error":"Shop was deleted","Code":"shop_deleted"}`,
+		},
+		{ // non-existing shop
+			"shop[93]",
+			404,
+			`{"Error":"Shop not found","Code":"shop_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.suid)
+
+		if assert.NoError(t, testCore.getShop(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.True(t, strings.HasPrefix(rec.Body.String(), tt.wantBody.(string)), rec.Body.String())
+		}
+	}
+}
+
+func TestGetShops(t *testing.T) {
+	var resp struct {
+		Data []models.ShopOutput
+	}
+
+	testCore := assembleTestCore()
+	req := httptest.NewRequest("", "/", nil)
+	rec := httptest.NewRecorder()
+	c := testCore.echo.NewContext(req, rec)
+
+	if assert.NoError(t, testCore.getShops(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		assert.Len(t, resp.Data, 2)
+	}
+
+	rec = httptest.NewRecorder()
+	c = testCore.echo.NewContext(req, rec)
+	c.Set("uid", "uuid.v6[1]")
+
+	if assert.NoError(t, testCore.getMyShops(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		if assert.Len(t, resp.Data, 1) {
+			assert.Equal(t, "shop[1]", resp.Data[0].ShopUID)
+		}
+	}
+}
+
+func TestUpdateShop(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		suid     string
+		uid      string
+		roles    []string
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"shop[1]",
+			"uuid.v6[1]",
+			[]string{"seller"},
+			`{"Description":"Updated description"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // admin updates foreign shop
+			"shop[2]",
+			"uuid.v6[6]",
+			[]string{"admin"},
+			`{"Name":"RenamedShop"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // seller updates foreign shop
+			"shop[2]",
+			"uuid.v6[1]",
+			[]string{"seller"},
+			`{"Name":"RenamedShop"}`,
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // deleted shop
+			"shop[3]",
+			"uuid.v6[1]",
+			[]string{"seller"},
+			`{"Name":"RenamedShop"}`,
+			404,
+			`{"Error":"Shop was deleted","Code":"shop_deleted"}`,
+		},
+		{ // wrong json
+			"shop[1]",
+			"uuid.v6[1]",
+			[]string{"seller"},
+			`{"Name":"RenamedShop",}`,
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // nothing to update
+			"shop[1]",
+			"uuid.v6[1]",
+			[]string{"seller"},
+			`{}`,
+			400,
+			`{"Error":"Nothing to update","Code":"nothing_to_update"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.suid)
+		c.Set("uid", tt.uid)
+		c.Set("roles", tt.roles)
+
+		if assert.NoError(t, testCore.updateShop(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestDeleteShop(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		suid     string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"shop[1]",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // foreign shop
+			"shop[2]",
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // non-existing shop
+			"shop[93]",
+			404,
+			`{"Error":"Shop not found","Code":"shop_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.suid)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.deleteShop(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestCreateItem(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.50","InStock":5}`,
+			200,
+			`{"Data":"item[4]"}`,
+		},
+		{ // price as json number
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":10.5,"InStock":5}`,
+			200,
+			`{"Data":"item[4]"}`,
+		},
+		{ // item sold in kilograms
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.50","InStock":5,"MeasureUnit":"kg"}`,
+			200,
+			`{"Data":"item[4]"}`,
+		},
+		{ // fractional stock of an item sold in kilograms
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.50","InStock":"2.5","MeasureUnit":"kg"}`,
+			200,
+			`{"Data":"item[4]"}`,
+		},
+		{ // fractional stock of piece goods
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.50","InStock":"2.5"}`,
+			400,
+			`{"Error":"Quantity must be whole for this measure unit","Code":"fractional_quantity"}`,
+		},
+		{ // unknown measure unit
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.50","InStock":5,"MeasureUnit":"parsec"}`,
+			400,
+			`{"Error":"Provided measure unit does not exist","Code":"unknown_measure_unit"}`,
+		},
+		{ // price with more than two decimals
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"10.505","InStock":5}`,
+			400,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // non-positive price
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"0","InStock":5}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Price","Rule":"gt","Message":"Price must be greater than 0.00"}]}`,
+		},
+		{ // negative stock
+			`{"ShopUID":"shop[1]","Name":"NewItem","Vendor":"TestVendor","Price":"1","InStock":-1}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"InStock","Rule":"min","Message":"InStock must be at least 0.00"}]}`,
+		},
+		{ // foreign shop
+			`{"ShopUID":"shop[2]","Name":"NewItem","Vendor":"TestVendor","Price":"1","InStock":1}`,
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // deleted shop
+			`{"ShopUID":"shop[3]","Name":"NewItem","Vendor":"TestVendor","Price":"1","InStock":1}`,
+			404,
+			`{"Error":"Shop was deleted","Code":"shop_deleted"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.createItem(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestGetItem(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		iuid     string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"item[1]",
+			200,
+			`"Price":"19.99"`,
+		},
+		{ // deleted item
+			"item[3]",
+			404,
+			`{"Error":"Item was deleted","Code":"item_deleted"}`,
+		},
+		{ // non-existing item
+			"item[93]",
+			404,
+			`{"Error":"Item not found","Code":"item_not_found"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.iuid)
+
+		if assert.NoError(t, testCore.getItem(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantBody)
+		}
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		iuid     string
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"item[1]",
+			`{"Price":"25"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // negative price
+			"item[1]",
+			`{"Price":"-1"}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Price","Rule":"gt","Message":"Price must be greater than 0.00"}]}`,
+		},
+		{ // foreign item
+			"item[2]",
+			`{"Name":"Mine now"}`,
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // nothing to update
+			"item[1]",
+			`{}`,
+			400,
+			`{"Error":"Nothing to update","Code":"nothing_to_update"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.iuid)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.updateItem(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestGetShopItems(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		suid      string
+		wantItems []string
+	}{
+		{ // deleted items are not listed
+			"shop[1]",
+			[]string{"item[1]"},
+		},
+		{ // items of a deleted shop are not listed
+			"shop[3]",
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		var resp struct {
+			Data []models.ItemOutput
+		}
+
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.suid)
+
+		if assert.NoError(t, testCore.getShopItems(c)) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+
+			var got []string
+			for _, io := range resp.Data {
+				got = append(got, io.ItemUID)
+			}
+			assert.Equal(t, tt.wantItems, got, tt.suid)
+		}
+	}
+}
+
+func TestRestockItem(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		iuid     string
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // restock
+			"item[1]",
+			`{"Delta":5}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // write-off
+			"item[1]",
+			`{"Delta":-10}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // too much write-off
+			"item[1]",
+			`{"Delta":-11}`,
+			409,
+			`{"Error":"Stock can not become negative","Code":"negative_stock"}`,
+		},
+		{ // fractional delta of piece goods
+			"item[1]",
+			`{"Delta":"0.5"}`,
+			400,
+			`{"Error":"Quantity must be whole for this measure unit","Code":"fractional_quantity"}`,
+		},
+		{ // zero delta
+			"item[1]",
+			`{"Delta":0}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Delta","Rule":"required","Message":"Delta is required"}]}`,
+		},
+		{ // deleted item
+			"item[3]",
+			`{"Delta":1}`,
+			404,
+			`{"Error":"Item was deleted","Code":"item_deleted"}`,
+		},
+		{ // item of a deleted shop
+			"item[5]",
+			`{"Delta":1}`,
+			404,
+			`{"Error":"Shop was deleted","Code":"shop_deleted"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.iuid)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.restockItem(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestDeleteItem(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		iuid     string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"item[1]",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // foreign item
+			"item[2]",
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.iuid)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.deleteItem(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestSearchItems(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantItems []string
+		wantBody  interface{}
+	}{
+		{ // no filters
+			"/",
+			200,
+			[]string{"item[1]", "item[2]"},
+			nil,
+		},
+		{ // free text query
+			"/?q=foreign",
+			200,
+			[]string{"item[2]"},
+			nil,
+		},
+		{ // price range
+			"/?price_min=10&price_max=100.50",
+			200,
+			[]string{"item[1]"},
+			nil,
+		},
+		{ // shop filter
+			"/?shop=shop[1]&in_stock=true&sort=price_desc",
+			200,
+			[]string{"item[1]"},
+			nil,
+		},
+		{ // unknown sort
+			"/?sort=cheapest",
+			400,
+			nil,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"sort","Rule":"oneof","Message":"sort must be one of: price_asc, price_desc, newest, rating"}]}`,
+		},
+		{ // inverted price range
+			"/?price_min=100&price_max=10",
+			400,
+			nil,
+			`{"Error":"price_min is greater than price_max","Code":"bad_price_range"}`,
+		},
+		{ // malformed price
+			"/?price_min=ten",
+			400,
+			nil,
+			`{"Error":"Wrong data format","Code":"bad_format"}`,
+		},
+		{ // limit too big
+			"/?limit=1000",
+			400,
+			nil,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"limit","Rule":"max","Message":"limit must be at most 100"}]}`,
+		},
+		{ // broken cursor
+			"/?cursor=bad",
+			400,
+			nil,
+			`{"Error":"Wrong cursor","Code":"bad_cursor"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		var resp struct {
+			Data models.ItemSearchOutput
+		}
+
+		req := httptest.NewRequest("", tt.query, nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+
+		if assert.NoError(t, testCore.searchItems(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code, tt.query)
+			if tt.wantBody != nil {
+				assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+				continue
+			}
+
+			var got []string
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			for _, io := range resp.Data.Items {
+				got = append(got, io.ItemUID)
+			}
+			assert.Equal(t, tt.wantItems, got, tt.query)
+		}
+	}
+}
+
+func TestCart(t *testing.T) {
+	testCore := assembleTestCore()
+
+	steps := []struct {
+		handler   func(echo.Context) error
+		param     string
+		input     string
+		wantCode  int
+		wantTotal string
+		wantBody  interface{}
+	}{
+		{ // add item
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[1]","Quantity":2}`,
+			200,
+			"39.98",
+			nil,
+		},
+		{ // add same item once more, quantities are summed
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[1]","Quantity":"3","MeasureUnit":"pcs"}`,
+			200,
+			"99.95",
+			nil,
+		},
+		{ // summed quantity exceeds stock
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[1]","Quantity":6}`,
+			409,
+			"",
+			`{"Error":"Not enough items in stock","Code":"out_of_stock"}`,
+		},
+		{ // summed quantity does not fit the cart line
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[1]","Quantity":"99999999.99"}`,
+			400,
+			"",
+			`{"Error":"Amount is too large","Code":"decimal_overflow"}`,
+		},
+		{ // measure unit differs from the item's one
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[1]","Quantity":1,"MeasureUnit":"kg"}`,
+			400,
+			"",
+			`{"Error":"Measure unit does not match the item","Code":"measure_unit_mismatch"}`,
+		},
+		{ // fractional quantity of piece goods
+			testCore.updateCartItem,
+			"",
+			`{"ItemUID":"item[1]","Quantity":"1.5"}`,
+			400,
+			"",
+			`{"Error":"Quantity must be whole for this measure unit","Code":"fractional_quantity"}`,
+		},
+		{ // fractional quantity of an item sold in kilograms, charged as is
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[2]","Quantity":"1.5"}`,
+			200,
+			"849.95",
+			nil,
+		},
+		{ // same item with its own measure unit
+			testCore.updateCartItem,
+			"",
+			`{"ItemUID":"item[2]","Quantity":"0.25","MeasureUnit":"kg"}`,
+			200,
+			"224.95",
+			nil,
+		},
+		{ // zero quantity
+			testCore.updateCartItem,
+			"",
+			`{"ItemUID":"item[1]","Quantity":0}`,
+			400,
+			"",
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Quantity","Rule":"gt","Message":"Quantity must be greater than 0.00"}]}`,
+		},
+		{ // deleted item
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[3]","Quantity":1}`,
+			404,
+			"",
+			`{"Error":"Item was deleted","Code":"item_deleted"}`,
+		},
+		{ // item of a deleted shop
+			testCore.addToCart,
+			"",
+			`{"ItemUID":"item[5]","Quantity":1}`,
+			404,
+			"",
+			`{"Error":"Shop was deleted","Code":"shop_deleted"}`,
+		},
+		{ // view cart
+			testCore.getCart,
+			"",
+			"",
+			200,
+			"224.95",
+			nil,
+		},
+		{ // remove item
+			testCore.removeFromCart,
+			"item[1]",
+			"",
+			200,
+			"125.00",
+			nil,
+		},
+		{ // remove item that is not in the cart
+			testCore.removeFromCart,
+			"item[1]",
+			"",
+			404,
+			"",
+			`{"Error":"Item is not in the cart","Code":"not_in_cart"}`,
+		},
+	}
+
+	for i, st := range steps {
+		var resp struct {
+			Data models.CartOutput
+		}
+
+		req := httptest.NewRequest("", "/", strings.NewReader(st.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(st.param)
+		c.Set("uid", "uuid.v6[1]")
+
+		if assert.NoError(t, st.handler(c)) {
+			assert.Equal(t, st.wantCode, rec.Code, "step %v", i)
+			if st.wantBody != nil {
+				assert.Equal(t, st.wantBody, strings.TrimSpace(rec.Body.String()), "step %v", i)
+				continue
+			}
+
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, st.wantTotal, resp.Data.Total.String(), "step %v", i)
+		}
+	}
+}
+
+func TestCartDeletedShop(t *testing.T) {
+	var resp struct {
+		Data models.CartOutput
+	}
+
+	testCore := assembleTestCore()
+
+	// shop was deleted while its item was already in the cart
+	if err := testCore.carts.Put(context.Background(), "uuid.v6[9]", "item[5]", 100, "unit[2]"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("", "/", nil)
+	rec := httptest.NewRecorder()
+	c := testCore.echo.NewContext(req, rec)
+	c.Set("uid", "uuid.v6[9]")
+
+	if assert.NoError(t, testCore.getCart(c)) {
+		assert.Equal(t, 200, rec.Code)
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		if assert.Len(t, resp.Data.Lines, 1) {
+			assert.True(t, resp.Data.Lines[0].Deleted)
+			assert.False(t, resp.Data.Lines[0].Available)
+		}
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		uid      string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"uuid.v6[1]",
+			200,
+			`{"Data":"order[1]"}`,
+		},
+		{ // empty cart
+			"uuid.v6[6]",
+			400,
+			`{"Error":"Cart is empty","Code":"cart_empty"}`,
+		},
+		{ // out of stock
+			"uuid.v6[4]",
+			409,
+			`{"Error":"Not enough items in stock: ForeignItem","Code":"out_of_stock"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("uid", tt.uid)
+
+		if assert.NoError(t, testCore.checkout(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestCheckoutChangesItemETag(t *testing.T) {
+	testCore := assembleTestCore()
+
+	getItem := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues("item[2]")
+
+		assert.NoError(t, testCore.getItem(c))
+		return rec
+	}
+
+	etag := getItem("").Header().Get("ETag")
+	assert.Equal(t, http.StatusNotModified, getItem(etag).Code)
+
+	// Из корзины uuid.v6[1] оформляется заказ с item[2], его остаток меняется
+	time.Sleep(time.Millisecond)
+	rec := httptest.NewRecorder()
+	c := testCore.echo.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+	c.Set("uid", "uuid.v6[1]")
+	if assert.NoError(t, testCore.checkout(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	after := getItem(etag)
+	assert.Equal(t, http.StatusOK, after.Code)
+	assert.NotEqual(t, etag, after.Header().Get("ETag"))
+}
+
+func TestGetOrder(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		ouid     string
+		uid      string
+		wantCode int
+	}{
+		{ // buyer
+			"order[1]",
+			"uuid.v6[1]",
+			200,
+		},
+		{ // seller of an item in the order
+			"order[2]",
+			"uuid.v6[1]",
+			200,
+		},
+		{ // seller of one of the items in the order
+			"order[3]",
+			"uuid.v6[1]",
+			200,
+		},
+		{ // stranger
+			"order[1]",
+			"uuid.v6[4]",
+			403,
+		},
+		{ // non-existing order
+			"order[93]",
+			"uuid.v6[1]",
+			404,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.ouid)
+		c.Set("uid", tt.uid)
+
+		if assert.NoError(t, testCore.getOrder(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+		}
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		ouid     string
+		uid      string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"order[1]",
+			"uuid.v6[1]",
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // already shipped
+			"order[2]",
+			"uuid.v6[9]",
+			409,
+			`{"Error":"Order status transition is not allowed: from shipped to cancelled","Code":"bad_transition"}`,
+		},
+		{ // someone else's order
+			"order[2]",
+			"uuid.v6[1]",
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.ouid)
+		c.Set("uid", tt.uid)
+
+		if assert.NoError(t, testCore.cancelOrder(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestAdvanceOrder(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		ouid     string
+		input    string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // good request
+			"order[2]",
+			`{"Status":"delivered"}`,
+			200,
+			`{"Data":"OK"}`,
+		},
+		{ // skipping a step
+			"order[2]",
+			`{"Status":"refunded"}`,
+			409,
+			`{"Error":"Order status transition is not allowed: from shipped to refunded","Code":"bad_transition"}`,
+		},
+		{ // unknown status
+			"order[2]",
+			`{"Status":"lost"}`,
+			400,
+			`{"Error":"Data validation failed","Code":"validation_failed","Fields":[{"Field":"Status","Rule":"oneof","Message":"Status must be one of: paid, shipped, delivered, cancelled, refunded"}]}`,
+		},
+		{ // order without seller's items
+			"order[1]",
+			`{"Status":"paid"}`,
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // order with items of another seller too
+			"order[3]",
+			`{"Status":"shipped"}`,
+			403,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", strings.NewReader(tt.input))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.ouid)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.advanceOrder(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
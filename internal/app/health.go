@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Статусы проверок /healthz и /readyz
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// healthCheck - результат одной проверки
+type healthCheck struct {
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+	Detail string `json:"Detail,omitempty"`
+}
+
+// healthReport - ответ /healthz и /readyz: общий статус и проверки по отдельности
+type healthReport struct {
+	Status string        `json:"Status"`
+	Checks []healthCheck `json:"Checks,omitempty"`
+}
+
+// healthz() - хэндлер проверки живости: процесс запущен и отвечает, зависимости не проверяются
+func (ac *core) healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, healthReport{Status: healthOK})
+}
+
+/*
+readyz() - хэндлер проверки готовности принимать запросы.
+Сервис не готов, если БД не отвечает за ReadyTimeout, схема БД отстает от миграций,
+вшитых в бинарник, или сервер уже останавливается. Тогда ответ 503 и в Checks видно,
+какая проверка не прошла. Причины ошибок БД пишутся только в лог.
+*/
+func (ac *core) readyz(c echo.Context) error {
+	ctx, cancel := context.WithTimeout(c.Request().Context(), ac.config.ReadyTimeout.Duration)
+	defer cancel()
+
+	report := healthReport{
+		Status: healthOK,
+		Checks: []healthCheck{
+			ac.checkDatabase(ctx, c),
+			ac.checkMigrations(ctx, c),
+			ac.checkShutdown(),
+		},
+	}
+
+	code := http.StatusOK
+	for _, check := range report.Checks {
+		if check.Status != healthOK {
+			report.Status = healthFail
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	return c.JSON(code, report)
+}
+
+// checkDatabase() - БД отвечает на ping
+func (ac *core) checkDatabase(ctx context.Context, c echo.Context) healthCheck {
+	check := healthCheck{Name: "database", Status: healthOK}
+
+	if err := ac.health.Ping(ctx); err != nil {
+		ac.reqLog(c).Warn("Readiness check failed", "check", check.Name, "err", err)
+		check.Status, check.Detail = healthFail, unavailable(err)
+	}
+
+	return check
+}
+
+/*
+checkMigrations() - в БД применены все миграции, которые знает бинарник.
+Схема новее бинарника готовности не мешает: так бывает во время выкладки,
+когда новая версия уже мигрировала БД, а старая еще обслуживает запросы.
+*/
+func (ac *core) checkMigrations(ctx context.Context, c echo.Context) healthCheck {
+	check := healthCheck{Name: "migrations", Status: healthOK}
+
+	version, err := ac.health.SchemaVersion(ctx)
+	switch {
+	case err != nil:
+		ac.reqLog(c).Warn("Readiness check failed", "check", check.Name, "err", err)
+		check.Status, check.Detail = healthFail, unavailable(err)
+	case version < ac.schemaVersion:
+		check.Status = healthFail
+		check.Detail = fmt.Sprintf("schema version %d, expected %d", version, ac.schemaVersion)
+	}
+
+	return check
+}
+
+// checkShutdown() - сервер не начал останавливаться
+func (ac *core) checkShutdown() healthCheck {
+	check := healthCheck{Name: "shutdown", Status: healthOK}
+
+	if atomic.LoadInt32(&ac.draining) != 0 {
+		check.Status, check.Detail = healthFail, "shutting down"
+	}
+
+	return check
+}
+
+// unavailable() - описание ошибки БД для ответа без подробностей подключения
+func unavailable(err error) string {
+	if interrupted(err) {
+		return "timed out"
+	}
+
+	return "unavailable"
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/pkg/models/mock"
+)
+
+type slowHealth struct {
+	mock.HealthModel
+}
+
+func (s *slowHealth) Ping(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		prepare  func(*core)
+		wantCode int
+		wantBody string
+	}{
+		{ // everything is fine
+			func(*core) {},
+			http.StatusOK,
+			`{"Status":"ok","Checks":[{"Name":"database","Status":"ok"},{"Name":"migrations","Status":"ok"},{"Name":"shutdown","Status":"ok"}]}`,
+		},
+		{ // schema is newer than the binary during a deploy
+			func(ac *core) { ac.health = &mock.HealthModel{Version: ac.schemaVersion + 1} },
+			http.StatusOK,
+			`{"Status":"ok","Checks":[{"Name":"database","Status":"ok"},{"Name":"migrations","Status":"ok"},{"Name":"shutdown","Status":"ok"}]}`,
+		},
+		{ // database is down
+			func(ac *core) { ac.health = &mock.HealthModel{Down: true} },
+			http.StatusServiceUnavailable,
+			`{"Status":"fail","Checks":[{"Name":"database","Status":"fail","Detail":"unavailable"},{"Name":"migrations","Status":"fail","Detail":"unavailable"},{"Name":"shutdown","Status":"ok"}]}`,
+		},
+		{ // database does not answer in time
+			func(ac *core) {
+				ac.config.ReadyTimeout.Duration = 10 * time.Millisecond
+				ac.health = &slowHealth{mock.HealthModel{Version: ac.schemaVersion}}
+			},
+			http.StatusServiceUnavailable,
+			`{"Status":"fail","Checks":[{"Name":"database","Status":"fail","Detail":"timed out"},{"Name":"migrations","Status":"fail","Detail":"timed out"},{"Name":"shutdown","Status":"ok"}]}`,
+		},
+		{ // migrations are not applied yet
+			func(ac *core) { ac.health = &mock.HealthModel{Version: 1}; ac.schemaVersion = 3 },
+			http.StatusServiceUnavailable,
+			`{"Status":"fail","Checks":[{"Name":"database","Status":"ok"},{"Name":"migrations","Status":"fail","Detail":"schema version 1, expected 3"},{"Name":"shutdown","Status":"ok"}]}`,
+		},
+		{ // server is draining requests
+			func(ac *core) { ac.draining = 1 },
+			http.StatusServiceUnavailable,
+			`{"Status":"fail","Checks":[{"Name":"database","Status":"ok"},{"Name":"migrations","Status":"ok"},{"Name":"shutdown","Status":"fail","Detail":"shutting down"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		testCore := assembleTestCore()
+		tt.prepare(testCore)
+
+		rec := httptest.NewRecorder()
+		testCore.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		assert.Equal(t, tt.wantCode, rec.Code)
+		assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	testCore := assembleTestCore()
+	testCore.health = &mock.HealthModel{Down: true}
+
+	rec := httptest.NewRecorder()
+	testCore.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code, "liveness does not depend on the database")
+	assert.Equal(t, `{"Status":"ok"}`, strings.TrimSpace(rec.Body.String()))
+}
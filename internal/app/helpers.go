@@ -1,17 +1,46 @@
 package app
 
 import (
-	"fmt"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
 	"net/http"
 	"runtime/debug"
+	"strings"
+	"time"
 
+	ut "github.com/go-playground/universal-translator"
+	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+
+	"github.com/JohanVong/online_bazaar/internal/i18n"
+	"github.com/JohanVong/online_bazaar/internal/logger"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+	"github.com/JohanVong/online_bazaar/tools"
+)
+
+const (
+	// accessTokenTTL - время жизни токена доступа
+	accessTokenTTL = time.Minute * 3
+	// refreshTokenTTL - время жизни refresh-токена
+	refreshTokenTTL = time.Hour * 24 * 30
+	// mimeMergePatch - тип тела JSON Merge Patch (RFC 7396)
+	mimeMergePatch = "application/merge-patch+json"
 )
 
 // apiResponse - структура ответа приложения
 type apiResponse struct {
-	Data  interface{} `json:"Data,omitempty"`
-	Error string      `json:"Error,omitempty"`
+	Data      interface{}        `json:"Data,omitempty"`
+	Error     string             `json:"Error,omitempty"`
+	Code      string             `json:"Code,omitempty"`
+	Fields    []tools.FieldError `json:"Fields,omitempty"`
+	RequestID string             `json:"RequestID,omitempty"`
 }
 
 // respondOK() - метод приложения для успешного ответа
@@ -23,61 +52,159 @@ func (ac *core) respondOK(data interface{}) (int, interface{}) {
 	if data != nil {
 		resp.Data = data
 	}
-	ac.infoLog.Println("Request successful")
 
 	return http.StatusOK, resp
 }
 
-// validationError() - отдает клиенту ошибку валидации
-func (ac *core) validationError(err error) (int, interface{}) {
-	resp := apiResponse{
-		Error: "Data validation failed",
+/*
+respondETag() - успешный ответ с ETag - версией записи из ее истории (models.Version).
+Если клиент прислал If-None-Match с той же версией, тело не отдается: 304 Not Modified.
+Для изменения записи клиент возвращает ETag в If-Match, см. ifMatch().
+*/
+func (ac *core) respondETag(c echo.Context, data interface{}, version string) error {
+	tag := `"` + version + `"`
+	h := c.Response().Header()
+	h.Set("ETag", tag)
+	// Ответ может зависеть от токена, поэтому общим кэшам его хранить нельзя, а свой кэш клиент сверяет по ETag
+	h.Set(echo.HeaderCacheControl, "private, no-cache")
+
+	if etagMatch(c.Request().Header.Get("If-None-Match"), tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(ac.respondOK(data))
+}
+
+/*
+etagMatch() - есть ли tag в значении If-None-Match: это * или список ETag через запятую.
+Слабые ETag (W/"...") сравниваются по значению, как требует RFC 9110 для If-None-Match.
+*/
+func etagMatch(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
 	}
-	ac.errorLog.Println(err.Error())
+
+	return false
+}
+
+// translator() - переводчик сообщений на язык из заголовка Accept-Language запроса
+func (ac *core) translator(c echo.Context) ut.Translator {
+	return ac.catalog.Translator(c.Request().Header.Get("Accept-Language"))
+}
+
+// reqLog() - логгер текущего запроса, его записи несут request_id
+func (ac *core) reqLog(c echo.Context) *logger.Logger {
+	return ac.log.Ctx(c.Request().Context())
+}
+
+/*
+errorResponse() - тело ответа с ошибкой: стабильный код, текст на языке клиента
+и идентификатор запроса, по которому ошибку можно найти в логах
+*/
+func (ac *core) errorResponse(c echo.Context, code, text string) apiResponse {
+	return apiResponse{
+		Error:     i18n.Text(ac.translator(c), code, text),
+		Code:      code,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+}
+
+// validationError() - отдает клиенту ошибку валидации со списком полей, которые ее не прошли
+func (ac *core) validationError(c echo.Context, err error) (int, interface{}) {
+	resp := ac.errorResponse(c, "validation_failed", "Data validation failed")
+	resp.Fields = tools.FieldErrors(err, ac.translator(c))
+	ac.reqLog(c).Warn("Validation failed", "err", err)
 
 	return http.StatusBadRequest, resp
 }
 
 // bindError() - отдает клиенту ошибку о неправильном формате входных данных
-func (ac *core) bindError(err error) (int, interface{}) {
-	resp := apiResponse{
-		Error: "Wrong data format",
-	}
-	ac.errorLog.Println(err.Error())
+func (ac *core) bindError(c echo.Context, err error) (int, interface{}) {
+	resp := ac.errorResponse(c, "bad_format", "Wrong data format")
+	ac.reqLog(c).Warn("Request data is malformed", "err", err)
 
 	return http.StatusBadRequest, resp
 }
 
-// unauthorized() - метод приложения для ответа со статусом Unauthorized
-func (ac *core) unauthorized(text string) (int, interface{}) {
-	resp := apiResponse{
-		Error: text,
+// tokenReused() - отзывает цепочку refresh-токенов, которую пытались использовать повторно
+func (ac *core) tokenReused(c echo.Context, familyUID string) (int, interface{}) {
+	err := ac.tokens.RevokeFamily(c.Request().Context(), familyUID)
+	if err != nil {
+		return ac.respondError(c, err)
 	}
-	ac.errorLog.Println(text)
 
-	return http.StatusUnauthorized, resp
+	return ac.respondError(c, models.ErrTokenReused)
 }
 
-// serverError() - метод приложения для ответа и обработки внутренней ошибки сервера
-func (ac *core) serverError(err error) (int, interface{}) {
-	resp := apiResponse{
-		Error: err.Error(),
+// errorStatus - HTTP статусы для видов доменных ошибок
+var errorStatus = map[models.Kind]int{
+	models.KindInvalid:      http.StatusBadRequest,
+	models.KindUnauthorized: http.StatusUnauthorized,
+	models.KindForbidden:    http.StatusForbidden,
+	models.KindNotFound:     http.StatusNotFound,
+	models.KindConflict:     http.StatusConflict,
+	models.KindPrecondition: http.StatusPreconditionFailed,
+}
+
+/*
+respondError() - единая точка перевода ошибок в ответ клиенту.
+Доменные ошибки models получают статус по своему виду и стабильный код,
+прерванные запросы - 503 или 504, все остальное - 500 без подробностей.
+Текст для клиента переводится по коду, полный текст ошибки с причиной попадает только в лог:
+ошибки клиента с уровнем warn, ошибки сервера - с уровнем error.
+*/
+func (ac *core) respondError(c echo.Context, err error) (int, interface{}) {
+	var (
+		de     *models.Error
+		status int
+		resp   apiResponse
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		status, resp = http.StatusServiceUnavailable, ac.errorResponse(c, "cancelled", "Request was cancelled")
+	case interrupted(err):
+		status, resp = http.StatusGatewayTimeout, ac.errorResponse(c, "timeout", "Request timed out")
+	case errors.As(err, &de) && errorStatus[de.Kind] != 0:
+		status, resp = errorStatus[de.Kind], ac.errorResponse(c, de.Code, de.Message)
+		if de.Detail != "" {
+			resp.Error += ": " + de.Detail
+		}
+	default:
+		status, resp = http.StatusInternalServerError, ac.errorResponse(c, "internal", "Internal server error")
 	}
-	ac.errorLog.Println(err.Error())
 
-	return http.StatusInternalServerError, resp
+	if status >= http.StatusInternalServerError {
+		ac.reqLog(c).Error("Request failed", "code", resp.Code, "err", err)
+	} else {
+		ac.reqLog(c).Warn("Request rejected", "code", resp.Code, "err", err)
+	}
+
+	return status, resp
 }
 
-// appPanic() - метод для отдачи наружу паники
-func (ac *core) appPanic(err error) (int, interface{}) {
-	resp := apiResponse{
-		Error: err.Error(),
+/*
+interrupted() - проверяет, что запрос к БД прерван: истек контекст запроса,
+его отменили или постгрес снял запрос по statement_timeout (код 57014).
+Такие ошибки нельзя выдавать за "не найдено" или "нет доступа".
+*/
+func interrupted(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "57014"
 	}
 
-	trace := fmt.Sprintf("%s\n%s", err.Error(), debug.Stack())
-	ac.errorLog.Println(trace)
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// appPanic() - метод для отдачи наружу паники, текст паники остается только в логе
+func (ac *core) appPanic(c echo.Context, err error) (int, interface{}) {
+	ac.reqLog(c).Error("Panic recovered", "err", err, "stack", string(debug.Stack()))
 
-	return http.StatusInternalServerError, resp
+	return http.StatusInternalServerError, ac.errorResponse(c, "internal", "Internal server error")
 }
 
 // generateToken() - метод для генерации токена
@@ -90,10 +217,241 @@ func (ac *core) generateToken(claims jwt.MapClaims, isStandard bool) (string, er
 		token = jwt.NewWithClaims(jwt.SigningMethodHS384, claims)
 	}
 
-	t, err := token.SignedString([]byte(ac.sign))
+	t, err := token.SignedString([]byte(ac.config.Sign))
 	if err != nil {
 		return "", err
 	}
 
 	return t, nil
 }
+
+// isOwner() - проверяет, что текущий пользователь владелец сущности или администратор
+func isOwner(c echo.Context, ownerUID string) bool {
+	if uid, _ := c.Get("uid").(string); uid != "" && uid == ownerUID {
+		return true
+	}
+
+	roles, _ := c.Get("roles").([]string)
+	for _, role := range roles {
+		if role == models.RoleAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ownShop() - достает действующий магазин и проверяет, что он принадлежит текущему пользователю.
+Если проверка не пройдена, магазин будет nil, а код и тело - готовым ответом клиенту.
+*/
+func (ac *core) ownShop(c echo.Context, suid string) (*models.ShopOutput, int, interface{}) {
+	so, err := ac.shops.Get(c.Request().Context(), suid)
+	if err != nil {
+		code, resp := ac.respondError(c, err)
+		return nil, code, resp
+	}
+
+	if !so.DeletedAt.IsZero() {
+		code, resp := ac.respondError(c, models.ErrShopDeleted)
+		return nil, code, resp
+	}
+
+	if !isOwner(c, so.OwnerUID) {
+		code, resp := ac.respondError(c, models.ErrAccessDenied)
+		return nil, code, resp
+	}
+
+	return so, 0, nil
+}
+
+// ownItem() - то же, что ownShop(), только для товара
+func (ac *core) ownItem(c echo.Context, iuid string) (*models.ItemOutput, int, interface{}) {
+	io, err := ac.activeItem(c.Request().Context(), iuid)
+	if err != nil {
+		code, resp := ac.respondError(c, err)
+		return nil, code, resp
+	}
+
+	if !isOwner(c, io.OwnerUID) {
+		code, resp := ac.respondError(c, models.ErrAccessDenied)
+		return nil, code, resp
+	}
+
+	return io, 0, nil
+}
+
+// activeItem() - отдает товар, если и он, и его магазин не удалены, иначе ErrItemDeleted или ErrShopDeleted
+func (ac *core) activeItem(ctx context.Context, iuid string) (*models.ItemOutput, error) {
+	io, err := ac.items.Get(ctx, iuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !io.DeletedAt.IsZero() {
+		return nil, models.ErrItemDeleted
+	}
+
+	so, err := ac.shops.Get(ctx, io.ShopUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !so.DeletedAt.IsZero() {
+		return nil, models.ErrShopDeleted
+	}
+
+	return io, nil
+}
+
+/*
+checkCartLine() - проверяет строку корзины: товар и его магазин действуют, единица измерения, если указана,
+совпадает с единицей товара, для штучных единиц количество целое, и оно не превышает остаток.
+Отдает ключ единицы измерения товара, либо пустой ключ и готовый ответ клиенту.
+*/
+func (ac *core) checkCartLine(c echo.Context, iuid string, quantity models.Decimal, unit string) (string, int, interface{}) {
+	ctx := c.Request().Context()
+
+	io, err := ac.activeItem(ctx, iuid)
+	if err != nil {
+		code, resp := ac.respondError(c, err)
+		return "", code, resp
+	}
+
+	if unit != "" && unit != io.MeasureUnit {
+		code, resp := ac.respondError(c, models.ErrUnitMismatch)
+		return "", code, resp
+	}
+
+	mu, err := ac.units.GetByName(ctx, io.MeasureUnit)
+	if err != nil {
+		code, resp := ac.respondError(c, err)
+		return "", code, resp
+	}
+
+	if !mu.Fractional && !quantity.IsWhole() {
+		code, resp := ac.respondError(c, models.ErrFractionalQuantity)
+		return "", code, resp
+	}
+
+	if quantity > io.InStock {
+		code, resp := ac.respondError(c, models.ErrOutOfStock)
+		return "", code, resp
+	}
+
+	if _, err = io.Price.Mul(quantity); err != nil {
+		code, resp := ac.respondError(c, err)
+		return "", code, resp
+	}
+
+	return mu.UUID, 0, nil
+}
+
+// sellsInOrder() - проверяет, что в заказе есть товары из магазинов текущего пользователя (или он админ)
+func sellsInOrder(c echo.Context, oo *models.OrderOutput) bool {
+	for _, l := range oo.Lines {
+		if isOwner(c, l.OwnerUID) {
+			return true
+		}
+	}
+
+	return isOwner(c, "")
+}
+
+/*
+sellsWholeOrder() - проверяет, что все товары заказа из магазинов текущего пользователя (или он админ).
+Статус у заказа один на все строки, поэтому продавец одной из строк не может менять его за остальных.
+*/
+func sellsWholeOrder(c echo.Context, oo *models.OrderOutput) bool {
+	if isOwner(c, "") {
+		return true
+	}
+
+	for _, l := range oo.Lines {
+		if !isOwner(c, l.OwnerUID) {
+			return false
+		}
+	}
+
+	return len(oo.Lines) > 0
+}
+
+// accessToken() - метод для генерации токена доступа пользователя с его ролями
+func (ac *core) accessToken(uid string, roles []string) (string, error) {
+	jti, err := uuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	claims["UID"] = uid
+	claims["Roles"] = roles
+	claims["jti"] = jti.String()
+	claims["iat"] = claimTime(now)
+	claims["exp"] = now.Add(accessTokenTTL).Unix()
+
+	return ac.generateToken(claims, true)
+}
+
+/*
+claimTime() - время для iat с точностью до микросекунды (NumericDate может быть дробным, RFC 7519).
+С целыми секундами токен, выпущенный в ту же секунду, что и /logout/all, но после него,
+считался бы отозванным.
+*/
+func claimTime(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
+
+// parseClaimTime() - время из iat, записанного claimTime() или целыми секундами
+func parseClaimTime(v float64) time.Time {
+	return time.UnixMicro(int64(math.Round(v * 1e6)))
+}
+
+// newRefreshToken() - генерирует случайный refresh-токен и его хэш для хранения в БД
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+// hashRefreshToken() - хэш refresh-токена, под которым он хранится в БД
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+missingUserHash() - хэш-заглушка с текущими параметрами хэшера. Пароль несуществующего пользователя
+сверяется с ним, поэтому такой вход занимает столько же времени, сколько и вход существующего.
+Что пароль заглушки известен, не страшно: совпадение ни на что не влияет. Считается один раз.
+*/
+func (ac *core) missingUserHash() string {
+	ac.dummyHashOnce.Do(func() {
+		var err error
+		ac.dummyHash, err = ac.hasher.Hash("missing user")
+		if err != nil {
+			ac.log.Error("Dummy password hash failed", "err", err)
+		}
+	})
+
+	return ac.dummyHash
+}
+
+// rehashPassword() - метод для пересчета устаревшего хэша пароля пользователя
+func (ac *core) rehashPassword(ctx context.Context, uid, password string) {
+	hash, err := ac.hasher.Hash(password)
+	if err != nil {
+		ac.log.Ctx(ctx).Error("Password rehash failed", "uid", uid, "err", err)
+		return
+	}
+
+	err = ac.users.UpdateHash(ctx, uid, hash)
+	if err != nil {
+		ac.log.Ctx(ctx).Error("Password rehash failed", "uid", uid, "err", err)
+	}
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/JohanVong/online_bazaar/internal/metrics"
+)
+
+// appMetrics - метрики приложения, отдаются на /metrics
+type appMetrics struct {
+	registry      *metrics.Registry
+	requests      *metrics.Counter
+	latency       *metrics.Histogram
+	signups       *metrics.Counter
+	logins        *metrics.Counter
+	loginFailures *metrics.Counter
+	ordersCreated *metrics.Counter
+}
+
+// newAppMetrics() - регистрирует метрики HTTP и предметные счетчики
+func newAppMetrics() *appMetrics {
+	r := metrics.NewRegistry()
+
+	return &appMetrics{
+		registry: r,
+		requests: r.NewCounter("http_requests_total",
+			"HTTP requests served, by method, route and status.", "method", "route", "status"),
+		latency: r.NewHistogram("http_request_duration_seconds",
+			"Time to serve an HTTP request, by method, route and status.", metrics.DefBuckets, "method", "route", "status"),
+		signups:       r.NewCounter("bazaar_signups_total", "Users signed up."),
+		logins:        r.NewCounter("bazaar_logins_total", "Successful logins."),
+		loginFailures: r.NewCounter("bazaar_login_failures_total", "Logins rejected because of a wrong username or password."),
+		ordersCreated: r.NewCounter("bazaar_orders_created_total", "Orders created at checkout."),
+	}
+}
+
+/*
+registerDBStats() - подключает статистику пула соединений из sql.DB.Stats().
+Значения читаются в момент запроса /metrics, поэтому отдельного сборщика не нужно.
+*/
+func (m *appMetrics) registerDBStats(conn *sql.DB) {
+	gauges := []struct {
+		name, help string
+		value      func(sql.DBStats) float64
+	}{
+		{"db_connections_max_open", "Maximum number of open connections to the database.",
+			func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"db_connections_open", "Established connections, both in use and idle.",
+			func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"db_connections_in_use", "Connections currently in use.",
+			func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"db_connections_idle", "Idle connections.",
+			func(s sql.DBStats) float64 { return float64(s.Idle) }},
+	}
+	for _, g := range gauges {
+		value := g.value
+		m.registry.NewGaugeFunc(g.name, g.help, func() float64 { return value(conn.Stats()) })
+	}
+
+	counters := []struct {
+		name, help string
+		value      func(sql.DBStats) float64
+	}{
+		{"db_wait_count_total", "Connections waited for.",
+			func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"db_wait_duration_seconds_total", "Time blocked waiting for a new connection.",
+			func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.",
+			func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+	for _, c := range counters {
+		value := c.value
+		m.registry.NewCounterFunc(c.name, c.help, func() float64 { return value(conn.Stats()) })
+	}
+}
+
+/*
+measure() - миддлвер, который считает запросы и время их обработки по маршрутам echo.
+Метка route - шаблон маршрута (/shop/:uid), а не сам путь, чтобы число рядов не росло от uid.
+Ошибки, которые хэндлеры вернули наверх, сначала отдаются клиенту, чтобы в метрику попал настоящий статус.
+*/
+func (ac *core) measure(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		route := c.Path()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Response().Status)
+
+		ac.metrics.requests.Inc(c.Request().Method, route, status)
+		ac.metrics.latency.Observe(time.Since(start).Seconds(), c.Request().Method, route, status)
+
+		return nil
+	}
+}
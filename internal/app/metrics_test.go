@@ -0,0 +1,63 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	testCore := assembleTestCore()
+
+	conn, err := sql.Open("postgres", "host=localhost dbname=none")
+	if assert.NoError(t, err) {
+		defer conn.Close()
+		testCore.metrics.registerDBStats(conn)
+	}
+
+	requests := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{http.MethodGet, "/shop/shop[1]", ""},
+		{http.MethodGet, "/shop/shop[2]", ""},
+		{http.MethodGet, "/shop/shop[3]", ""},
+		{http.MethodGet, "/no/such/route", ""},
+		{http.MethodPost, "/user/login", `{"Username":"TestUser","Password":"TestPassword"}`},
+		{http.MethodPost, "/user/login", `{"Username":"TestUser","Password":"WrongPassword"}`},
+		{http.MethodPost, "/user/login", `{"Username":"Nobody","Password":"TestPassword"}`},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.target, strings.NewReader(r.body))
+		req.Header.Set("Content-Type", "application/json")
+		testCore.echo.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	rec := httptest.NewRecorder()
+	testCore.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		// uid does not make new series, the route template is used
+		`http_requests_total{method="GET",route="/shop/:uid",status="200"} 2`,
+		`http_requests_total{method="GET",route="/shop/:uid",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_requests_total{method="POST",route="/user/login",status="401"} 2`,
+		`http_request_duration_seconds_count{method="POST",route="/user/login",status="200"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/shop/:uid",status="200",le="+Inf"} 2`,
+		`bazaar_signups_total 0`,
+		`bazaar_logins_total 1`,
+		`bazaar_login_failures_total 2`,
+		`bazaar_orders_created_total 0`,
+		`db_connections_open 0`,
+		`db_wait_count_total 0`,
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
@@ -1,18 +1,27 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
+
+	"github.com/JohanVong/online_bazaar/internal/logger"
+	"github.com/JohanVong/online_bazaar/pkg/models"
 )
 
 // authorize() - авторизационный миддлвер для пользователя
 func (ac *core) authorize(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		sign := []byte(ac.sign)
+		ctx := c.Request().Context()
+		sign := []byte(ac.config.Sign)
 
 		keyFunc := func(t *jwt.Token) (interface{}, error) {
 			if t.Method.Alg() != "HS256" {
@@ -26,38 +35,229 @@ func (ac *core) authorize(next echo.HandlerFunc) echo.HandlerFunc {
 
 		token, err := jwt.Parse(auth, keyFunc)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, map[string]string{"Error": err.Error()})
+			c.JSON(ac.respondError(c, models.ErrBadToken.Wrap(err)))
 			return err
 		}
 
 		claims := token.Claims.(jwt.MapClaims)
 		uid := claims["UID"].(string)
 
-		uo, err := ac.users.Get(uid, true)
+		jti, _ := claims["jti"].(string)
+		iat, _ := claims["iat"].(float64)
+		exp, _ := claims["exp"].(float64)
+		if jti == "" {
+			err = models.ErrBadToken.Wrap(errors.New("token has no id"))
+			c.JSON(ac.respondError(c, err))
+			return err
+		}
+
+		revoked, err := ac.revocations.IsRevoked(ctx, jti, uid, parseClaimTime(iat))
+		if err != nil {
+			return c.JSON(ac.respondError(c, err))
+		}
+
+		if revoked {
+			c.JSON(ac.respondError(c, models.ErrTokenRevoked))
+			return models.ErrTokenRevoked
+		}
+
+		uo, err := ac.users.Get(ctx, uid, true)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, map[string]string{"Error": err.Error()})
+			if !errors.Is(err, models.ErrUserNotFound) {
+				return c.JSON(ac.respondError(c, err))
+			}
+			err = models.ErrBadToken.Wrap(err)
+			c.JSON(ac.respondError(c, err))
 			return err
 		}
 
 		if !uo.DeletedAt.IsZero() {
-			c.JSON(http.StatusUnauthorized, map[string]string{"Error": "User was deleted"})
-			return err
+			c.JSON(ac.respondError(c, models.ErrUserDeleted))
+			return models.ErrUserDeleted
+		}
+
+		var roles []string
+		claimed, _ := claims["Roles"].([]interface{})
+		for _, r := range claimed {
+			if role, ok := r.(string); ok {
+				roles = append(roles, role)
+			}
 		}
 
 		c.Set("uid", uid)
+		c.Set("roles", roles)
+		c.Set("jti", jti)
+		c.Set("exp", time.Unix(int64(exp), 0))
+		return next(c)
+	}
+}
+
+/*
+requireRole() - фабрика миддлверов, пропускающих только пользователей хотя бы с одной из ролей.
+Роли берутся из токена, поэтому миддлвер должен стоять после authorize.
+*/
+func (ac *core) requireRole(allowed ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			roles, _ := c.Get("roles").([]string)
+
+			for _, role := range roles {
+				for _, a := range allowed {
+					if role == a {
+						return next(c)
+					}
+				}
+			}
+
+			return c.JSON(ac.respondError(c, models.ErrAccessDenied))
+		}
+	}
+}
+
+/*
+timeout() - миддлвер, ограничивающий время обработки запроса.
+По истечении RequestTimeout контекст запроса отменяется, и все запросы к БД,
+сделанные с этим контекстом, прерываются.
+*/
+func (ac *core) timeout(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), ac.config.RequestTimeout.Duration)
+		defer cancel()
+
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+/*
+requestID() - миддлвер, который присваивает запросу идентификатор.
+Идентификатор из заголовка X-Request-ID клиента или балансировщика сохраняется,
+если его нет или он подозрительный - генерируется новый. Он возвращается в том же заголовке
+и попадает во все записи лога, сделанные логгером запроса.
+*/
+func (ac *core) requestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			u, _ := uuid.NewV4()
+			id = u.String()
+		}
+
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+
+		ctx := logger.NewContext(c.Request().Context(), ac.log.With("request_id", id))
+		c.SetRequest(c.Request().WithContext(ctx))
 		return next(c)
 	}
 }
 
+// validRequestID() - чужой идентификатор принимается, только если он короткий и из печатных ASCII символов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+accessLog() - миддлвер, который пишет по записи на каждый запрос:
+метод, маршрут, статус, время обработки и пользователя, если он авторизован.
+Ошибки, которые хэндлеры вернули наверх, сначала отдаются клиенту, чтобы в лог попал настоящий статус.
+*/
+func (ac *core) accessLog(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		uid, _ := c.Get("uid").(string)
+		ac.reqLog(c).Info("Request",
+			"method", c.Request().Method,
+			"route", c.Path(),
+			"path", c.Request().URL.Path,
+			"status", c.Response().Status,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"uid", uid,
+		)
+
+		return nil
+	}
+}
+
 // recoverPanic() - миддлвер для обработки паник
 func (ac *core) recoverPanic(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		defer func() {
 			if err := recover(); err != nil {
-				c.JSON(ac.appPanic(fmt.Errorf("%s", err)))
+				c.JSON(ac.appPanic(c, fmt.Errorf("%s", err)))
 			}
 		}()
 
 		return next(c)
 	}
 }
+
+/*
+ifMatch() - миддлвер для условных изменений по If-Match (RFC 9110, 13.1.1).
+ETag записи - ее версия из истории (см. respondETag()), сравнение строгое: слабые ETag не совпадают.
+Версии из заголовка кладутся в контекст, и модель меняет запись, только если ее версия
+совпала с одной из них, иначе - 412 Precondition Failed. Без заголовка и с * изменение безусловное.
+*/
+func (ac *core) ifMatch(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := strings.TrimSpace(c.Request().Header.Get("If-Match"))
+		if header == "" || header == "*" {
+			return next(c)
+		}
+
+		var versions []time.Time
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+				continue
+			}
+
+			v, err := models.ParseVersion(tag[1 : len(tag)-1])
+			if err == nil {
+				versions = append(versions, v)
+			}
+		}
+
+		if len(versions) == 0 {
+			return c.JSON(ac.respondError(c, models.ErrVersionMismatch))
+		}
+
+		ctx := models.WithExpectedVersions(c.Request().Context(), versions)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+/*
+deprecated() - миддлвер для устаревших путей: Deprecation и Sunset с датами вывода из обращения
+и Link на тот же путь под префиксом successor
+*/
+func (ac *core) deprecated(successor string) echo.MiddlewareFunc {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+	sunset := legacySunsetAt.Format(http.TimeFormat)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			h := c.Response().Header()
+			h.Set("Deprecation", deprecation)
+			h.Set("Sunset", sunset)
+			h.Set("Link", "<"+successor+c.Request().URL.RequestURI()+`>; rel="successor-version"`)
+
+			return next(c)
+		}
+	}
+}
@@ -1,14 +1,27 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/internal/logger"
+	"github.com/JohanVong/online_bazaar/pkg/models"
 )
 
 func TestAuthorize(t *testing.T) {
@@ -20,56 +33,80 @@ func TestAuthorize(t *testing.T) {
 	tests := []struct {
 		alg      int
 		uid      string
+		jti      string
 		wantCode int
 		wantBody interface{}
 	}{
 		{ // pass middleware
 			2,
 			"uuid.v6[1]",
+			"jti[1]",
 			http.StatusOK,
 			`{"Data":"We are ok!"}`,
 		},
 		{ // wrongly signed token
 			3,
 			"uuid.v6[1]",
+			"jti[1]",
 			http.StatusUnauthorized,
-			`{"Error":"Wrong signing method"}`,
+			`{"Error":"Invalid token","Code":"invalid_token","RequestID":"req-auth"}`,
 		},
 		{ // bad token, parsing error
 			0,
 			"uuid.v6[1]",
+			"jti[1]",
 			http.StatusUnauthorized,
-			`{"Error":"token contains an invalid number of segments"}`,
+			`{"Error":"Invalid token","Code":"invalid_token","RequestID":"req-auth"}`,
 		},
 		{ // unexisting user tries to authorize
 			2,
 			"uuid.v6[93]",
+			"jti[1]",
 			http.StatusUnauthorized,
-			`{"Error":"No record found"}`,
+			`{"Error":"Invalid token","Code":"invalid_token","RequestID":"req-auth"}`,
 		},
 		{ // deleted user tries to authorize
 			2,
 			"uuid.v6[4]",
+			"jti[1]",
+			http.StatusUnauthorized,
+			`{"Error":"User was deleted","Code":"user_deleted","RequestID":"req-auth"}`,
+		},
+		{ // token without id
+			2,
+			"uuid.v6[1]",
+			"",
 			http.StatusUnauthorized,
-			`{"Error":"User was deleted"}`,
+			`{"Error":"Invalid token","Code":"invalid_token","RequestID":"req-auth"}`,
+		},
+		{ // revoked token
+			2,
+			"uuid.v6[1]",
+			"jti[revoked]",
+			http.StatusUnauthorized,
+			`{"Error":"Token was revoked","Code":"token_revoked","RequestID":"req-auth"}`,
 		},
 		{ // panic
 			2,
 			"panic",
+			"jti[1]",
 			http.StatusInternalServerError,
-			`{"Error":"test panic!"}`,
+			`{"Error":"Internal server error","Code":"internal","RequestID":"req-auth"}`,
 		},
 	}
 
 	testCore := assembleTestCore()
 	go func() {
-		testCore.errorLog.Fatal(testCore.echo.Start(":63246"))
+		log.Fatal(testCore.echo.Start(":63246"))
 	}()
 	time.Sleep(1 * time.Second)
+	testCore.revocations.Revoke(context.Background(), "jti[revoked]", time.Now().Add(time.Minute*3))
 
 	for _, tt := range tests {
 		claims := jwt.MapClaims{}
 		claims["UID"] = tt.uid
+		claims["jti"] = tt.jti
+		claims["iat"] = time.Now().Unix()
 		claims["exp"] = time.Now().Add(time.Minute * 3).Unix()
 
 		switch tt.alg {
@@ -89,6 +126,7 @@ func TestAuthorize(t *testing.T) {
 			t.Fatal(err)
 		}
 		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Request-ID", "req-auth")
 
 		res, err := http.DefaultClient.Do(req)
 		if res != nil {
@@ -103,3 +141,453 @@ func TestAuthorize(t *testing.T) {
 		assert.Equal(t, tt.wantBody, strings.TrimSpace(string(body)))
 	}
 }
+
+func TestAuthorizeRevokedAll(t *testing.T) {
+	testCore := assembleTestCore()
+	h := testCore.authorize(testCore.testAlive)
+
+	second := time.Now().Add(-time.Minute).Truncate(time.Second)
+	revokedAt := second.Add(500 * time.Millisecond)
+	testCore.revocations.RevokeAll(context.Background(), "uuid.v6[1]", revokedAt)
+
+	tests := []struct {
+		issuedAt time.Time
+		wantCode int
+	}{
+		{ // issued before the revocation in the same second
+			second.Add(400 * time.Millisecond),
+			http.StatusUnauthorized,
+		},
+		{ // issued at the moment of the revocation
+			revokedAt,
+			http.StatusUnauthorized,
+		},
+		{ // issued after the revocation in the same second
+			second.Add(600 * time.Millisecond),
+			http.StatusOK,
+		},
+		{ // issued in the next second
+			second.Add(time.Second),
+			http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		claims := jwt.MapClaims{}
+		claims["UID"] = "uuid.v6[1]"
+		claims["jti"] = "jti[" + tt.issuedAt.Format("05.000") + "]"
+		claims["iat"] = claimTime(tt.issuedAt)
+		claims["exp"] = time.Now().Add(time.Minute).Unix()
+
+		token, err := testCore.generateToken(claims, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+
+		h(testCore.echo.NewContext(req, rec))
+		assert.Equal(t, tt.wantCode, rec.Code, tt.issuedAt.Format("05.000"))
+	}
+
+	issued := time.Now()
+	assert.Equal(t, issued.Truncate(time.Microsecond).UnixNano(), parseClaimTime(claimTime(issued)).UnixNano(), "iat keeps microseconds")
+}
+
+func TestRequireRole(t *testing.T) {
+	testCore := assembleTestCore()
+	h := testCore.requireRole("seller", "admin")(testCore.testAlive)
+
+	tests := []struct {
+		roles    []string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // one of allowed roles
+			[]string{"buyer", "seller"},
+			http.StatusOK,
+			`{"Data":"We are ok!"}`,
+		},
+		{ // no allowed roles
+			[]string{"buyer"},
+			http.StatusForbidden,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+		{ // no roles at all
+			nil,
+			http.StatusForbidden,
+			`{"Error":"Access denied","Code":"access_denied"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest("", "/", nil)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.Set("roles", tt.roles)
+
+		if assert.NoError(t, h(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	testCore := assembleTestCore()
+	h := testCore.ifMatch(testCore.updateShop)
+
+	so, err := testCore.shops.Get(context.Background(), "shop[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := `"` + models.Version(so.CreatedAt, so.UpdatedAt) + `"`
+	stale := `"` + models.Version(so.CreatedAt.Add(-time.Second), time.Time{}) + `"`
+	mismatch := `{"Error":"Record was changed since it was read","Code":"version_mismatch"}`
+
+	tests := []struct {
+		ifMatch  string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // unconditional change
+			"",
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+		{ // record has not changed
+			current,
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+		{ // one of the listed versions is current
+			stale + ", " + current,
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+		{ // any version
+			"*",
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+		{ // record was changed by someone else
+			stale,
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+		{ // weak tags never match in the strong comparison
+			"W/" + current,
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+		{ // not a version at all
+			`"not a version!"`,
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"Name":"RenamedShop"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.ifMatch != "" {
+			req.Header.Set("If-Match", tt.ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues("shop[1]")
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, h(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code, tt.ifMatch)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()), tt.ifMatch)
+		}
+	}
+}
+
+func TestIfMatchDelete(t *testing.T) {
+	testCore := assembleTestCore()
+	ctx := context.Background()
+	mismatch := `{"Error":"Record was changed since it was read","Code":"version_mismatch"}`
+
+	uo, err := testCore.users.Get(ctx, "uuid.v6[1]", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	so, err := testCore.shops.Get(ctx, "shop[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io, err := testCore.items.Get(ctx, "item[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := func(created, updated time.Time) string {
+		return `"` + models.Version(created, updated) + `"`
+	}
+
+	tests := []struct {
+		handler  func(echo.Context) error
+		param    string
+		ifMatch  string
+		wantCode int
+		wantBody interface{}
+	}{
+		{ // stale user
+			testCore.deleteUser,
+			"",
+			tag(uo.CreatedAt.Add(-time.Second), time.Time{}),
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+		{ // stale shop
+			testCore.deleteShop,
+			"shop[1]",
+			tag(so.CreatedAt.Add(-time.Second), time.Time{}),
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+		{ // stale item
+			testCore.deleteItem,
+			"item[1]",
+			tag(io.CreatedAt.Add(-time.Second), time.Time{}),
+			http.StatusPreconditionFailed,
+			mismatch,
+		},
+		{ // current shop
+			testCore.deleteShop,
+			"shop[1]",
+			tag(so.CreatedAt, so.UpdatedAt),
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+		{ // current item
+			testCore.deleteItem,
+			"item[1]",
+			tag(io.CreatedAt, io.UpdatedAt),
+			http.StatusOK,
+			`{"Data":"OK"}`,
+		},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodDelete, "/", nil)
+		req.Header.Set("If-Match", tt.ifMatch)
+		rec := httptest.NewRecorder()
+		c := testCore.echo.NewContext(req, rec)
+		c.SetParamNames("uid")
+		c.SetParamValues(tt.param)
+		c.Set("uid", "uuid.v6[1]")
+		c.Set("roles", []string{"seller"})
+
+		if assert.NoError(t, testCore.ifMatch(tt.handler)(c)) {
+			assert.Equal(t, tt.wantCode, rec.Code, "case %v", i)
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()), "case %v", i)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		target   string
+		timeout  time.Duration
+		ctx      context.Context
+		wantCode int
+		wantBody string
+	}{
+		{ // request fits into the timeout
+			"/country/list",
+			time.Second,
+			context.Background(),
+			http.StatusOK,
+			`{"Data":[`,
+		},
+		{ // lookup timed out, must not look like a missing shop
+			"/shop/shop[1]",
+			time.Nanosecond,
+			context.Background(),
+			http.StatusGatewayTimeout,
+			`{"Error":"Request timed out","Code":"timeout",`,
+		},
+		{ // list timed out
+			"/country/list",
+			time.Nanosecond,
+			context.Background(),
+			http.StatusGatewayTimeout,
+			`{"Error":"Request timed out","Code":"timeout",`,
+		},
+		{ // client went away
+			"/country/list",
+			time.Second,
+			cancelled,
+			http.StatusServiceUnavailable,
+			`{"Error":"Request was cancelled","Code":"cancelled",`,
+		},
+	}
+
+	for _, tt := range tests {
+		testCore := assembleTestCore()
+		testCore.config.RequestTimeout.Duration = tt.timeout
+
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil).WithContext(tt.ctx)
+		rec := httptest.NewRecorder()
+		testCore.echo.ServeHTTP(rec, req)
+
+		assert.Equal(t, tt.wantCode, rec.Code, tt.target)
+		assert.True(t, strings.HasPrefix(rec.Body.String(), tt.wantBody), rec.Body.String())
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		target   string
+		header   string
+		wantID   string
+		wantCode int
+		wantBody string
+	}{
+		{ // client's id is propagated
+			"/shop/shop[3]",
+			"req-42",
+			"req-42",
+			http.StatusNotFound,
+			`{"Error":"Shop was deleted","Code":"shop_deleted","RequestID":"req-42"}`,
+		},
+		{ // no id, a new one is generated
+			"/test/alive",
+			"",
+			"",
+			http.StatusOK,
+			"",
+		},
+		{ // id with spaces is replaced
+			"/test/alive",
+			"req 42",
+			"",
+			http.StatusOK,
+			"",
+		},
+		{ // too long id is replaced
+			"/test/alive",
+			strings.Repeat("a", 129),
+			"",
+			http.StatusOK,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		testCore := assembleTestCore()
+		testCore.log = logger.New(&buf, logger.LevelInfo)
+
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set("X-Request-ID", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		testCore.echo.ServeHTTP(rec, req)
+
+		id := rec.Header().Get("X-Request-ID")
+		if tt.wantID != "" {
+			assert.Equal(t, tt.wantID, id)
+		} else {
+			assert.Len(t, id, 36, tt.header)
+		}
+
+		assert.Equal(t, tt.wantCode, rec.Code)
+		if tt.wantBody != "" {
+			assert.Equal(t, tt.wantBody, strings.TrimSpace(rec.Body.String()))
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		var access map[string]interface{}
+		if assert.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &access)) {
+			assert.Equal(t, "Request", access["msg"])
+			assert.Equal(t, id, access["request_id"])
+			assert.Equal(t, float64(tt.wantCode), access["status"])
+			assert.Equal(t, tt.target, access["path"])
+		}
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	assert.True(t, interrupted(context.DeadlineExceeded))
+	assert.True(t, interrupted(fmt.Errorf("query: %w", context.Canceled)))
+	assert.True(t, interrupted(&pq.Error{Code: "57014"}))
+	assert.False(t, interrupted(&pq.Error{Code: "23505"}))
+	assert.False(t, interrupted(sql.ErrNoRows))
+}
+
+func TestRespondError(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		err      error
+		lang     string
+		wantCode int
+		wantBody apiResponse
+	}{
+		{ // domain error
+			models.ErrShopNotFound,
+			"",
+			http.StatusNotFound,
+			apiResponse{Error: "Shop not found", Code: "shop_not_found"},
+		},
+		{ // domain error with a cause, the cause stays in the log
+			models.ErrUserExists.Wrap(&pq.Error{Code: "23505", Message: "duplicate key"}),
+			"",
+			http.StatusConflict,
+			apiResponse{Error: "Username or email is already taken", Code: "user_exists"},
+		},
+		{ // domain error wrapped by fmt
+			fmt.Errorf("checkout: %w", models.ErrEmptyCart),
+			"",
+			http.StatusBadRequest,
+			apiResponse{Error: "Cart is empty", Code: "cart_empty"},
+		},
+		{ // translated domain error, detail is kept as is
+			models.ErrOutOfStock.Withf("%s", "TestItem"),
+			"ru-RU,ru;q=0.9,en;q=0.8",
+			http.StatusConflict,
+			apiResponse{Error: "Недостаточно товара на складе: TestItem", Code: "out_of_stock"},
+		},
+		{ // unsupported language falls back to the default one
+			models.ErrEmptyCart,
+			"de",
+			http.StatusBadRequest,
+			apiResponse{Error: "Cart is empty", Code: "cart_empty"},
+		},
+		{ // statement timeout
+			&pq.Error{Code: "57014"},
+			"ru",
+			http.StatusGatewayTimeout,
+			apiResponse{Error: "Превышено время обработки запроса", Code: "timeout"},
+		},
+		{ // unknown error is not shown to the client
+			errors.New("pq: connection refused"),
+			"",
+			http.StatusInternalServerError,
+			apiResponse{Error: "Internal server error", Code: "internal"},
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Language", tt.lang)
+		c := testCore.echo.NewContext(req, httptest.NewRecorder())
+
+		code, resp := testCore.respondError(c, tt.err)
+
+		assert.Equal(t, tt.wantCode, code, tt.err.Error())
+		assert.Equal(t, tt.wantBody, resp, tt.err.Error())
+	}
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/JohanVong/online_bazaar/internal/config"
+	"github.com/JohanVong/online_bazaar/internal/db/migrate"
+)
+
+const migrateUsage = "Usage: api migrate up | down [steps] | status | force <version>"
+
+/*
+Migrate() - подкоманда migrate: применяет, откатывает миграции, показывает их статус
+или отмечает схему версией без выполнения миграций (force).
+Отдает код выхода процесса.
+*/
+func Migrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	err := cfg.DB.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	conn, err := getConnDB(&cfg.DB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer conn.Close()
+
+	m := migrate.New(conn)
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up()
+		fmt.Printf("Applied %d migration(s)\n", n)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+
+		n, err := m.Down(steps)
+		fmt.Printf("Reverted %d migration(s)\n", n)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "force":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+
+		n, err := m.Force(version)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Marked schema as version %d, %d migration(s) changed\n", version, n)
+	case "status":
+		statuses, err := m.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		for _, s := range statuses {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-24s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
@@ -0,0 +1,336 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/JohanVong/online_bazaar/internal/openapi"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+/*
+apiOperation - описание маршрута для документа OpenAPI.
+body и query - типы тела и параметров строки запроса, data - тип поля Data в успешном ответе.
+Схемы строятся по самим структурам из pkg/models, поэтому новые поля и правила validate
+попадают в документ сами. Маршруты сверяются с configureRouting() в тестах.
+*/
+type apiOperation struct {
+	method  string
+	path    string
+	id      string
+	tag     string
+	summary string
+	auth    bool
+	roles   []string
+	body    interface{}
+	query   interface{}
+	data    interface{}
+	// versioned - маршрут есть только под /v1, без устаревшего псевдонима
+	versioned bool
+	// etag - ответ несет ETag (версию записи) и понимает If-None-Match
+	etag bool
+	// ifMatch - изменение можно сделать условным через If-Match с ETag записи
+	ifMatch bool
+	// raw - ответ не в обертке apiResponse (служебные маршруты)
+	raw map[string]*openapi.Response
+}
+
+// serviceOperations() - служебные маршруты без версии
+func serviceOperations() []apiOperation {
+	health := map[string]*openapi.Response{
+		"200": {Description: "Checks passed", Content: jsonContent(&openapi.Schema{Ref: "#/components/schemas/healthReport"})},
+		"503": {Description: "Some checks failed", Content: jsonContent(&openapi.Schema{Ref: "#/components/schemas/healthReport"})},
+	}
+
+	return []apiOperation{
+		{method: http.MethodGet, path: "/healthz", id: "healthz", tag: "service", summary: "Liveness: the process is up",
+			raw: map[string]*openapi.Response{"200": health["200"]}},
+		{method: http.MethodGet, path: "/readyz", id: "readyz", tag: "service", summary: "Readiness: database, migrations and shutdown checks",
+			raw: health},
+		{method: http.MethodGet, path: "/metrics", id: "metrics", tag: "service", summary: "Prometheus metrics",
+			raw: map[string]*openapi.Response{"200": {Description: "Metrics in the Prometheus text format",
+				Content: map[string]*openapi.MediaType{"text/plain": {Schema: &openapi.Schema{Type: "string"}}}}}},
+		{method: http.MethodGet, path: "/openapi.json", id: "openapi", tag: "service", summary: "This document",
+			raw: map[string]*openapi.Response{"200": {Description: "OpenAPI document", Content: jsonContent(&openapi.Schema{Type: "object"})}}},
+		{method: http.MethodGet, path: "/docs", id: "docs", tag: "service", summary: "API documentation viewer",
+			raw: map[string]*openapi.Response{"200": {Description: "HTML page",
+				Content: map[string]*openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}}}},
+		{method: http.MethodGet, path: "/test/alive", id: "testAlive", tag: "service", summary: "Ping", data: ""},
+		{method: http.MethodGet, path: "/test/auth", id: "testAuth", tag: "service", summary: "Ping with a token check", auth: true, data: ""},
+	}
+}
+
+/*
+apiOperations() - маршруты апи в том же порядке, что и в routesV1(), пути без префикса версии.
+В документ они попадают под /v1 и еще раз без версии как устаревшие псевдонимы.
+*/
+func apiOperations() []apiOperation {
+	seller := []string{models.RoleSeller}
+	sellerOrAdmin := []string{models.RoleSeller, models.RoleAdmin}
+	admin := []string{models.RoleAdmin}
+
+	return []apiOperation{
+		{method: http.MethodPost, path: "/user/signup", id: "signupUser", tag: "user", summary: "Sign up",
+			body: models.UserSignupInput{}, data: ""},
+		{method: http.MethodPost, path: "/user/login", id: "loginUser", tag: "user", summary: "Log in and get a token pair",
+			body: models.UserLoginInput{}, data: models.UserLoginOutput{}},
+		{method: http.MethodPost, path: "/user/token/refresh", id: "refreshToken", tag: "user", summary: "Exchange a refresh token for a new token pair",
+			body: models.RefreshTokenInput{}, data: models.UserLoginOutput{}},
+		{method: http.MethodPost, path: "/user/logout", id: "logoutUser", tag: "user", summary: "Revoke the current token and optionally its refresh token",
+			auth: true, body: models.LogoutInput{}, data: ""},
+		{method: http.MethodPost, path: "/user/logout/all", id: "logoutUserAll", tag: "user", summary: "Revoke all tokens of the current user",
+			auth: true, data: ""},
+		{method: http.MethodGet, path: "/user/me", id: "getMe", tag: "user", summary: "Profile of the current user",
+			auth: true, data: models.UserProfileOutput{}, versioned: true, etag: true},
+		{method: http.MethodPut, path: "/user/update", id: "updateUser", tag: "user", summary: "Update email, phone or country",
+			auth: true, body: models.UserUpdateInput{}, data: "", ifMatch: true},
+		{method: http.MethodPatch, path: "/user", id: "patchUser", tag: "user", summary: "Update email, phone or country as a JSON Merge Patch, null clears the phone",
+			auth: true, body: models.UserUpdateInput{}, data: "", versioned: true, ifMatch: true},
+		{method: http.MethodPut, path: "/user/update/password", id: "updateUserPassword", tag: "user", summary: "Change password",
+			auth: true, body: models.UpdateUserPasswordInput{}, data: "", ifMatch: true},
+		{method: http.MethodDelete, path: "/user/delete", id: "deleteUser", tag: "user", summary: "Delete the current user",
+			auth: true, data: "", ifMatch: true},
+
+		{method: http.MethodPost, path: "/admin/role/grant", id: "grantRole", tag: "admin", summary: "Grant a role",
+			auth: true, roles: admin, body: models.RoleInput{}, data: ""},
+		{method: http.MethodPost, path: "/admin/role/revoke", id: "revokeRole", tag: "admin", summary: "Revoke a role",
+			auth: true, roles: admin, body: models.RoleInput{}, data: ""},
+
+		{method: http.MethodGet, path: "/shop/list", id: "getShops", tag: "shop", summary: "List shops",
+			data: []*models.ShopOutput{}},
+		{method: http.MethodGet, path: "/shop/my", id: "getMyShops", tag: "shop", summary: "List shops of the current user",
+			auth: true, data: []*models.ShopOutput{}},
+		{method: http.MethodGet, path: "/shop/:uid", id: "getShop", tag: "shop", summary: "Get a shop",
+			data: models.ShopOutput{}, etag: true},
+		{method: http.MethodPost, path: "/shop/create", id: "createShop", tag: "shop", summary: "Create a shop, returns its key",
+			auth: true, roles: seller, body: models.ShopInput{}, data: ""},
+		{method: http.MethodPut, path: "/shop/update/:uid", id: "updateShop", tag: "shop", summary: "Update own shop",
+			auth: true, roles: sellerOrAdmin, body: models.ShopUpdateInput{}, data: "", ifMatch: true},
+		{method: http.MethodDelete, path: "/shop/delete/:uid", id: "deleteShop", tag: "shop", summary: "Delete own shop",
+			auth: true, roles: sellerOrAdmin, data: "", ifMatch: true},
+
+		{method: http.MethodGet, path: "/item/search", id: "searchItems", tag: "item", summary: "Search items with cursor pagination",
+			query: models.ItemSearchInput{}, data: models.ItemSearchOutput{}},
+		{method: http.MethodGet, path: "/item/:uid", id: "getItem", tag: "item", summary: "Get an item",
+			data: models.ItemOutput{}, etag: true},
+		{method: http.MethodGet, path: "/item/shop/:uid", id: "getShopItems", tag: "item", summary: "List items of a shop",
+			data: []*models.ItemOutput{}},
+		{method: http.MethodPost, path: "/item/create", id: "createItem", tag: "item", summary: "Create an item in own shop, returns its key",
+			auth: true, roles: seller, body: models.ItemInput{}, data: ""},
+		{method: http.MethodPut, path: "/item/update/:uid", id: "updateItem", tag: "item", summary: "Update own item",
+			auth: true, roles: sellerOrAdmin, body: models.ItemUpdateInput{}, data: "", ifMatch: true},
+		{method: http.MethodPut, path: "/item/restock/:uid", id: "restockItem", tag: "item", summary: "Change stock of own item",
+			auth: true, roles: sellerOrAdmin, body: models.ItemRestockInput{}, data: "", ifMatch: true},
+		{method: http.MethodDelete, path: "/item/delete/:uid", id: "deleteItem", tag: "item", summary: "Delete own item",
+			auth: true, roles: sellerOrAdmin, data: "", ifMatch: true},
+
+		{method: http.MethodGet, path: "/cart", id: "getCart", tag: "cart", summary: "Get the cart",
+			auth: true, data: models.CartOutput{}},
+		{method: http.MethodPost, path: "/cart/add", id: "addToCart", tag: "cart", summary: "Add an item, quantities are summed",
+			auth: true, body: models.CartItemInput{}, data: models.CartOutput{}},
+		{method: http.MethodPut, path: "/cart/update", id: "updateCartItem", tag: "cart", summary: "Set quantity of an item",
+			auth: true, body: models.CartItemInput{}, data: models.CartOutput{}},
+		{method: http.MethodDelete, path: "/cart/remove/:uid", id: "removeFromCart", tag: "cart", summary: "Remove an item",
+			auth: true, data: models.CartOutput{}},
+		{method: http.MethodDelete, path: "/cart/clear", id: "clearCart", tag: "cart", summary: "Clear the cart",
+			auth: true, data: ""},
+
+		{method: http.MethodPost, path: "/order/checkout", id: "checkout", tag: "order", summary: "Create an order from the cart, returns its key",
+			auth: true, data: ""},
+		{method: http.MethodGet, path: "/order/list", id: "getOrders", tag: "order", summary: "List orders of the current user",
+			auth: true, data: []*models.OrderOutput{}},
+		{method: http.MethodGet, path: "/order/:uid", id: "getOrder", tag: "order", summary: "Get an order with lines and transitions",
+			auth: true, data: models.OrderOutput{}, etag: true},
+		{method: http.MethodPost, path: "/order/cancel/:uid", id: "cancelOrder", tag: "order", summary: "Cancel own order",
+			auth: true, data: "", ifMatch: true},
+		{method: http.MethodPost, path: "/order/advance/:uid", id: "advanceOrder", tag: "order", summary: "Move an order to the next status",
+			auth: true, roles: sellerOrAdmin, body: models.OrderStatusInput{}, data: "", ifMatch: true},
+
+		{method: http.MethodGet, path: "/country/list", id: "getCountries", tag: "reference", summary: "List countries",
+			data: []*models.CountryOutput{}},
+		{method: http.MethodGet, path: "/unit/list", id: "getUnits", tag: "reference", summary: "List measure units",
+			data: []*models.MeasureUnitOutput{}},
+	}
+}
+
+// pathParam - параметр пути echo (:uid)
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+/*
+apiDocument() - собирает документ OpenAPI по apiOperations().
+Ответы с ошибкой описаны схемой Error, которая строится по apiResponse без поля Data.
+*/
+func apiDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:   "online_bazaar API",
+		Version: "1.0.0",
+		Description: "Successful responses wrap the result in Data, errors carry a stable Code. " +
+			"Messages follow Accept-Language, every response has an X-Request-ID header. " +
+			"Routes live under /v1, unversioned paths are deprecated aliases. " +
+			"Single records carry an ETag; send it back in If-Match to change or delete the record " +
+			"only if nobody changed it since, otherwise the response is 412 with Code version_mismatch.",
+	})
+	doc.DefineType(models.Decimal(0), &openapi.Schema{
+		Type:    "string",
+		Pattern: `^-?\d+(\.\d{1,2})?$`,
+		Example: "12.50",
+	})
+	doc.DefineType(models.NullString{}, &openapi.Schema{
+		Type:        "string",
+		Nullable:    true,
+		Description: "May be omitted to keep the current value",
+	})
+	doc.Components.SecuritySchemes["bearer"] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+
+	doc.Schema(healthReport{})
+	errSchema := apiErrorSchema(doc)
+
+	for _, ao := range serviceOperations() {
+		doc.Add(ao.method, pathParam.ReplaceAllString(ao.path, "{$1}"), apiOperationDoc(doc, ao, errSchema))
+	}
+
+	legacy := fmt.Sprintf("Deprecated alias of /v1%%s, responses carry Deprecation and Sunset headers. Sunset: %s.",
+		legacySunsetAt.Format("2006-01-02"))
+	for _, ao := range apiOperations() {
+		path := pathParam.ReplaceAllString(ao.path, "{$1}")
+		doc.Add(ao.method, "/v1"+path, apiOperationDoc(doc, ao, errSchema))
+		if ao.versioned {
+			continue
+		}
+
+		op := apiOperationDoc(doc, ao, errSchema)
+		op.OperationID += "Unversioned"
+		op.Description = strings.TrimSpace(fmt.Sprintf(legacy, path) + " " + op.Description)
+		op.Deprecated = true
+		doc.Add(ao.method, path, op)
+	}
+
+	return doc
+}
+
+// apiOperationDoc() - описание одной операции: параметры, тело, авторизация и ответы
+func apiOperationDoc(doc *openapi.Document, ao apiOperation, errSchema *openapi.Schema) *openapi.Operation {
+	op := &openapi.Operation{
+		OperationID: ao.id,
+		Summary:     ao.summary,
+		Tags:        []string{ao.tag},
+		Responses:   ao.raw,
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(ao.path, -1) {
+		op.Parameters = append(op.Parameters, &openapi.Parameter{
+			Name: m[1], In: "path", Required: true, Schema: &openapi.Schema{Type: "string"},
+		})
+	}
+	if ao.query != nil {
+		op.Parameters = append(op.Parameters, doc.QueryParameters(ao.query)...)
+	}
+	if ao.body != nil {
+		op.RequestBody = &openapi.RequestBody{Required: true, Content: jsonContent(doc.Schema(ao.body))}
+		if ao.method == http.MethodPatch {
+			op.RequestBody.Content[mimeMergePatch] = op.RequestBody.Content[echo.MIMEApplicationJSON]
+		}
+	}
+	if ao.auth {
+		op.Security = []map[string][]string{{"bearer": {}}}
+	}
+	if len(ao.roles) > 0 {
+		op.Description = "Requires one of the roles: " + strings.Join(ao.roles, ", ") + "."
+	}
+
+	if ao.raw == nil {
+		op.Responses = apiResponses(doc, ao, errSchema)
+	}
+	if ao.etag {
+		op.Parameters = append(op.Parameters, &openapi.Parameter{
+			Name: "If-None-Match", In: "header", Schema: &openapi.Schema{Type: "string"},
+			Description: "ETag of a cached response, if the record has not changed the response is 304 without a body",
+		})
+		op.Responses["200"].Headers = map[string]*openapi.Header{
+			"ETag": {Description: "Version of the record, send it back in If-Match to change the record", Schema: &openapi.Schema{Type: "string"}},
+		}
+		op.Responses["304"] = &openapi.Response{Description: "Cached response is still current"}
+	}
+	if ao.ifMatch {
+		op.Parameters = append(op.Parameters, &openapi.Parameter{
+			Name: "If-Match", In: "header", Schema: &openapi.Schema{Type: "string"},
+			Description: "ETag of the record as it was read, the change is applied only if the record has not changed since",
+		})
+		op.Responses["412"] = &openapi.Response{Description: "Record was changed since it was read", Content: jsonContent(errSchema)}
+	}
+
+	return op
+}
+
+// apiResponses() - успешный ответ в обертке Data и ответы с ошибкой, которые может отдать маршрут
+func apiResponses(doc *openapi.Document, ao apiOperation, errSchema *openapi.Schema) map[string]*openapi.Response {
+	ok := &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"Data": doc.Schema(ao.data)},
+		Required:   []string{"Data"},
+	}
+
+	responses := map[string]*openapi.Response{
+		"200":     {Description: "Success", Content: jsonContent(ok)},
+		"default": {Description: "Error, see Code", Content: jsonContent(errSchema)},
+	}
+
+	failure := func(code, description string) {
+		responses[code] = &openapi.Response{Description: description, Content: jsonContent(errSchema)}
+	}
+	if ao.body != nil || ao.query != nil {
+		failure("400", "Malformed request or validation failed, see Fields")
+	}
+	if ao.auth {
+		failure("401", "Token is missing, invalid or revoked")
+	}
+	if len(ao.roles) > 0 {
+		failure("403", "User has none of the required roles")
+	}
+	if pathParam.MatchString(ao.path) {
+		failure("404", "Not found")
+	}
+
+	return responses
+}
+
+// apiErrorSchema() - схема Error из полей apiResponse, кроме Data
+func apiErrorSchema(doc *openapi.Document) *openapi.Schema {
+	doc.Schema(apiResponse{})
+
+	s := doc.Components.Schemas["apiResponse"]
+	delete(doc.Components.Schemas, "apiResponse")
+	delete(s.Properties, "Data")
+	s.Required = []string{"Error", "Code"}
+	doc.Components.Schemas["Error"] = s
+
+	return &openapi.Schema{Ref: "#/components/schemas/Error"}
+}
+
+func jsonContent(s *openapi.Schema) map[string]*openapi.MediaType {
+	return map[string]*openapi.MediaType{echo.MIMEApplicationJSON: {Schema: s}}
+}
+
+/*
+configureDocs() - собирает документ OpenAPI один раз при старте
+и подключает /openapi.json и страницу /docs для его чтения
+*/
+func (ac *core) configureDocs() {
+	spec, err := json.Marshal(apiDocument())
+	if err != nil {
+		// Документ собирается из статичных структур, ошибка здесь - ошибка в коде
+		panic(err)
+	}
+
+	ac.echo.GET("/openapi.json", func(c echo.Context) error {
+		return c.JSONBlob(http.StatusOK, spec)
+	})
+	ac.echo.GET("/docs", func(c echo.Context) error {
+		return c.HTMLBlob(http.StatusOK, openapi.Viewer)
+	})
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOpenAPIRoutes - документ и configureRouting() описывают одни и те же маршруты
+func TestOpenAPIRoutes(t *testing.T) {
+	testCore := assembleTestCore()
+
+	// Группы с миддлверами echo закрывает своими маршрутами 404 на все методы
+	notFound := runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+
+	var registered []string
+	for _, r := range testCore.echo.Routes() {
+		if r.Name == notFound {
+			continue
+		}
+		registered = append(registered, r.Method+" "+pathParam.ReplaceAllString(r.Path, "{$1}"))
+	}
+
+	var documented []string
+	for path, item := range apiDocument().Paths {
+		for method := range *item {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, registered, documented, "routes in configureRouting() and apiOperations() differ")
+}
+
+// TestOpenAPISchemas - все ссылки на схемы разрешаются, у маршрутов с uid есть параметр пути
+func TestOpenAPISchemas(t *testing.T) {
+	doc := apiDocument()
+
+	b, err := json.Marshal(doc)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/(\w+)"`).FindAllSubmatch(b, -1)
+	assert.NotEmpty(t, refs)
+	for _, ref := range refs {
+		assert.Contains(t, doc.Components.Schemas, string(ref[1]))
+	}
+
+	ids := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			assert.False(t, ids[op.OperationID], "duplicate operationId %s", op.OperationID)
+			ids[op.OperationID] = true
+
+			if strings.Contains(path, "{uid}") && assert.NotEmpty(t, op.Parameters, method+" "+path) {
+				assert.Equal(t, "uid", op.Parameters[0].Name)
+			}
+		}
+	}
+
+	signup := doc.Components.Schemas["UserSignupInput"]
+	if assert.NotNil(t, signup) {
+		assert.Equal(t, []string{"Username", "Password", "Email"}, signup.Required)
+		assert.Equal(t, "email", signup.Properties["Email"].Format)
+		assert.Equal(t, 8, *signup.Properties["Password"].MinLength)
+	}
+	assert.NotContains(t, doc.Components.Schemas["ItemOutput"].Properties, "OwnerUID", "hidden from JSON")
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		target      string
+		contentType string
+		wantBody    string
+	}{
+		{ // the document
+			"/openapi.json",
+			"application/json; charset=UTF-8",
+			`{"openapi":"3.0.3",`,
+		},
+		{ // the viewer
+			"/docs",
+			"text/html; charset=UTF-8",
+			"<!DOCTYPE html>",
+		},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		testCore.echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(rec.Body.String(), tt.wantBody), tt.target)
+	}
+}
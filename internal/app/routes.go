@@ -1,18 +1,201 @@
 package app
 
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+/*
+Старые пути без версии (/user/signup) - псевдонимы /v1 для клиентов, выпущенных
+до версионирования. Они отвечают как /v1, но с заголовками Deprecation (RFC 9745)
+и Sunset (RFC 8594), после даты Sunset их можно удалять.
+*/
+var (
+	legacyDeprecatedAt = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	legacySunsetAt     = time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC)
+)
+
+// route - маршрут внутри группы апи
+type route struct {
+	method     string
+	path       string
+	handler    echo.HandlerFunc
+	middleware []echo.MiddlewareFunc
+	// versioned - маршрут появился после версионирования, псевдонима без версии у него нет
+	versioned bool
+}
+
+// routeGroup - группа маршрутов с общим префиксом и миддлверами, как echo.Group
+type routeGroup struct {
+	prefix     string
+	middleware []echo.MiddlewareFunc
+	routes     []route
+}
+
 // configureRouting() - метод для конфигурации раутера
 func (ac *core) configureRouting() {
+	ac.echo.Use(ac.requestID)
+	ac.echo.Use(ac.accessLog)
+	ac.echo.Use(ac.measure)
 	ac.echo.Use(ac.recoverPanic)
+	ac.echo.Use(ac.timeout)
+	ac.echo.GET("/healthz", ac.healthz)
+	ac.echo.GET("/readyz", ac.readyz)
+	ac.echo.GET("/metrics", echo.WrapHandler(ac.metrics.registry))
+	ac.configureDocs()
 	ac.echo.GET("/test/alive", ac.testAlive)
 	ac.echo.GET("/test/auth", ac.testAlive, ac.authorize)
 
-	ug := ac.echo.Group("/user")
-	ug.POST("/signup", ac.signupUser)
-	ug.POST("/login", ac.loginUser)
-	ug.PUT("/update", ac.updateUser, ac.authorize)
-	ug.PUT("/update/password", ac.updateUserPassword, ac.authorize)
-	ug.DELETE("/delete", ac.deleteUser, ac.authorize)
+	v1 := ac.routesV1()
+	ac.mount("/v1", v1)
+	ac.mount("", unversioned(v1), ac.deprecated("/v1"))
+
+	// Следующая версия переопределяет только изменившиеся маршруты, остальные берет из v1:
+	// ac.mount("/v2", override(v1, []routeGroup{{prefix: "/user", routes: []route{...}}}))
+}
+
+// routesV1() - маршруты первой версии апи
+func (ac *core) routesV1() []routeGroup {
+	seller := ac.requireRole(models.RoleSeller)
+	sellerOrAdmin := ac.requireRole(models.RoleSeller, models.RoleAdmin)
+
+	return []routeGroup{
+		{prefix: "/user", routes: []route{
+			{method: http.MethodPost, path: "/signup", handler: ac.signupUser},
+			{method: http.MethodPost, path: "/login", handler: ac.loginUser},
+			{method: http.MethodPost, path: "/token/refresh", handler: ac.refreshToken},
+			{method: http.MethodPost, path: "/logout", handler: ac.logoutUser, middleware: mw(ac.authorize)},
+			{method: http.MethodPost, path: "/logout/all", handler: ac.logoutUserAll, middleware: mw(ac.authorize)},
+			{method: http.MethodGet, path: "/me", handler: ac.getMe, middleware: mw(ac.authorize), versioned: true},
+			{method: http.MethodPut, path: "/update", handler: ac.updateUser, middleware: mw(ac.authorize, ac.ifMatch)},
+			{method: http.MethodPatch, path: "", handler: ac.patchUser, middleware: mw(ac.authorize, ac.ifMatch), versioned: true},
+			{method: http.MethodPut, path: "/update/password", handler: ac.updateUserPassword, middleware: mw(ac.authorize, ac.ifMatch)},
+			{method: http.MethodDelete, path: "/delete", handler: ac.deleteUser, middleware: mw(ac.authorize, ac.ifMatch)},
+		}},
+		{prefix: "/admin", middleware: mw(ac.authorize, ac.requireRole(models.RoleAdmin)), routes: []route{
+			{method: http.MethodPost, path: "/role/grant", handler: ac.grantRole},
+			{method: http.MethodPost, path: "/role/revoke", handler: ac.revokeRole},
+		}},
+		{prefix: "/shop", routes: []route{
+			{method: http.MethodGet, path: "/list", handler: ac.getShops},
+			{method: http.MethodGet, path: "/my", handler: ac.getMyShops, middleware: mw(ac.authorize)},
+			{method: http.MethodGet, path: "/:uid", handler: ac.getShop},
+			{method: http.MethodPost, path: "/create", handler: ac.createShop, middleware: mw(ac.authorize, seller)},
+			{method: http.MethodPut, path: "/update/:uid", handler: ac.updateShop, middleware: mw(ac.authorize, sellerOrAdmin, ac.ifMatch)},
+			{method: http.MethodDelete, path: "/delete/:uid", handler: ac.deleteShop, middleware: mw(ac.authorize, sellerOrAdmin, ac.ifMatch)},
+		}},
+		{prefix: "/item", routes: []route{
+			{method: http.MethodGet, path: "/search", handler: ac.searchItems},
+			{method: http.MethodGet, path: "/:uid", handler: ac.getItem},
+			{method: http.MethodGet, path: "/shop/:uid", handler: ac.getShopItems},
+			{method: http.MethodPost, path: "/create", handler: ac.createItem, middleware: mw(ac.authorize, seller)},
+			{method: http.MethodPut, path: "/update/:uid", handler: ac.updateItem, middleware: mw(ac.authorize, sellerOrAdmin, ac.ifMatch)},
+			{method: http.MethodPut, path: "/restock/:uid", handler: ac.restockItem, middleware: mw(ac.authorize, sellerOrAdmin, ac.ifMatch)},
+			{method: http.MethodDelete, path: "/delete/:uid", handler: ac.deleteItem, middleware: mw(ac.authorize, sellerOrAdmin, ac.ifMatch)},
+		}},
+		{prefix: "/cart", middleware: mw(ac.authorize), routes: []route{
+			{method: http.MethodGet, path: "", handler: ac.getCart},
+			{method: http.MethodPost, path: "/add", handler: ac.addToCart},
+			{method: http.MethodPut, path: "/update", handler: ac.updateCartItem},
+			{method: http.MethodDelete, path: "/remove/:uid", handler: ac.removeFromCart},
+			{method: http.MethodDelete, path: "/clear", handler: ac.clearCart},
+		}},
+		{prefix: "/order", middleware: mw(ac.authorize), routes: []route{
+			{method: http.MethodPost, path: "/checkout", handler: ac.checkout},
+			{method: http.MethodGet, path: "/list", handler: ac.getOrders},
+			{method: http.MethodGet, path: "/:uid", handler: ac.getOrder},
+			{method: http.MethodPost, path: "/cancel/:uid", handler: ac.cancelOrder, middleware: mw(ac.ifMatch)},
+			{method: http.MethodPost, path: "/advance/:uid", handler: ac.advanceOrder, middleware: mw(sellerOrAdmin, ac.ifMatch)},
+		}},
+		{prefix: "/country", routes: []route{
+			{method: http.MethodGet, path: "/list", handler: ac.getCountries},
+		}},
+		{prefix: "/unit", routes: []route{
+			{method: http.MethodGet, path: "/list", handler: ac.getUnits},
+		}},
+	}
+}
+
+/*
+mount() - регистрирует группы маршрутов под префиксом версии.
+Миддлверы extra выполняются в каждой группе первыми, до авторизации,
+чтобы их заголовки попадали и в ответы с ошибкой.
+*/
+func (ac *core) mount(version string, groups []routeGroup, extra ...echo.MiddlewareFunc) {
+	for _, rg := range groups {
+		g := ac.echo.Group(version+rg.prefix, append(append([]echo.MiddlewareFunc(nil), extra...), rg.middleware...)...)
+		for _, r := range rg.routes {
+			g.Add(r.method, r.path, r.handler, r.middleware...)
+		}
+	}
+}
+
+/*
+override() - группы base, в которых маршруты из changes заменяют маршруты с тем же
+методом и путем, а новые маршруты и группы добавляются. Миддлверы группы из changes,
+если заданы, заменяют миддлверы группы base. Сам base не меняется.
+*/
+func override(base []routeGroup, changes []routeGroup) []routeGroup {
+	groups := make([]routeGroup, len(base))
+	index := make(map[string]int, len(base))
+	for i, rg := range base {
+		rg.routes = append([]route(nil), rg.routes...)
+		groups[i] = rg
+		index[rg.prefix] = i
+	}
+
+	for _, change := range changes {
+		i, ok := index[change.prefix]
+		if !ok {
+			index[change.prefix] = len(groups)
+			groups = append(groups, change)
+			continue
+		}
+
+		if change.middleware != nil {
+			groups[i].middleware = change.middleware
+		}
+
+	next:
+		for _, r := range change.routes {
+			for j, old := range groups[i].routes {
+				if old.method == r.method && old.path == r.path {
+					groups[i].routes[j] = r
+					continue next
+				}
+			}
+			groups[i].routes = append(groups[i].routes, r)
+		}
+	}
+
+	return groups
+}
+
+// unversioned() - группы только с маршрутами, у которых есть псевдоним без версии
+func unversioned(groups []routeGroup) []routeGroup {
+	var legacy []routeGroup
+	for _, rg := range groups {
+		routes := make([]route, 0, len(rg.routes))
+		for _, r := range rg.routes {
+			if !r.versioned {
+				routes = append(routes, r)
+			}
+		}
+
+		if len(routes) > 0 {
+			rg.routes = routes
+			legacy = append(legacy, rg)
+		}
+	}
+
+	return legacy
+}
 
-	cg := ac.echo.Group("/country")
-	cg.GET("/list", ac.getCountries)
+// mw() - короткая запись списка миддлверов
+func mw(m ...echo.MiddlewareFunc) []echo.MiddlewareFunc {
+	return m
 }
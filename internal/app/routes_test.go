@@ -0,0 +1,135 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionedRoutes(t *testing.T) {
+	testCore := assembleTestCore()
+
+	tests := []struct {
+		method     string
+		target     string
+		wantCode   int
+		deprecated bool
+		wantLink   string
+	}{
+		{ // current version
+			http.MethodGet,
+			"/v1/country/list",
+			http.StatusOK,
+			false,
+			"",
+		},
+		{ // unversioned alias answers the same
+			http.MethodGet,
+			"/country/list?lang=ru",
+			http.StatusOK,
+			true,
+			`</v1/country/list?lang=ru>; rel="successor-version"`,
+		},
+		{ // alias keeps the headers when authorization fails
+			http.MethodGet,
+			"/cart",
+			http.StatusUnauthorized,
+			true,
+			`</v1/cart>; rel="successor-version"`,
+		},
+		{ // current version of a protected route
+			http.MethodGet,
+			"/v1/cart",
+			http.StatusUnauthorized,
+			false,
+			"",
+		},
+		{ // routes added after versioning have no unversioned alias
+			http.MethodGet,
+			"/user/me",
+			http.StatusNotFound,
+			true,
+			`</v1/user/me>; rel="successor-version"`,
+		},
+		{ // service routes are not versioned
+			http.MethodGet,
+			"/healthz",
+			http.StatusOK,
+			false,
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, nil)
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		rec := httptest.NewRecorder()
+		testCore.echo.ServeHTTP(rec, req)
+
+		assert.Equal(t, tt.wantCode, rec.Code, tt.target)
+		if tt.deprecated {
+			assert.Equal(t, "@1792281600", rec.Header().Get("Deprecation"), tt.target)
+			assert.Equal(t, "Sun, 18 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"), tt.target)
+		} else {
+			assert.Empty(t, rec.Header().Get("Deprecation"), tt.target)
+			assert.Empty(t, rec.Header().Get("Sunset"), tt.target)
+		}
+		assert.Equal(t, tt.wantLink, rec.Header().Get("Link"), tt.target)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	handler := func(name string) echo.HandlerFunc {
+		return func(c echo.Context) error { return c.String(http.StatusOK, name) }
+	}
+	auth := func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+
+	base := []routeGroup{
+		{prefix: "/user", routes: []route{
+			{method: http.MethodPost, path: "/signup", handler: handler("v1 signup")},
+			{method: http.MethodPost, path: "/login", handler: handler("v1 login")},
+		}},
+		{prefix: "/unit", routes: []route{
+			{method: http.MethodGet, path: "/list", handler: handler("v1 units")},
+		}},
+	}
+
+	v2 := override(base, []routeGroup{
+		{prefix: "/user", routes: []route{
+			{method: http.MethodPost, path: "/login", handler: handler("v2 login")},
+			{method: http.MethodGet, path: "/me", handler: handler("v2 me"), middleware: mw(auth)},
+		}},
+		{prefix: "/unit", middleware: mw(auth)},
+		{prefix: "/wishlist", routes: []route{
+			{method: http.MethodGet, path: "", handler: handler("v2 wishlist")},
+		}},
+	})
+
+	ac := &core{echo: echo.New()}
+	ac.mount("/v2", v2)
+
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{http.MethodPost, "/v2/user/signup", "v1 signup"}, // inherited
+		{http.MethodPost, "/v2/user/login", "v2 login"},   // overridden
+		{http.MethodGet, "/v2/user/me", "v2 me"},          // added to a group
+		{http.MethodGet, "/v2/unit/list", "v1 units"},     // group middleware replaced, routes kept
+		{http.MethodGet, "/v2/wishlist", "v2 wishlist"},   // new group
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		ac.echo.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		assert.Equal(t, tt.want, rec.Body.String(), tt.target)
+	}
+
+	assert.Len(t, base[0].routes, 2, "base is not changed")
+	assert.Nil(t, base[1].middleware, "base is not changed")
+	assert.Len(t, v2[1].middleware, 1)
+}
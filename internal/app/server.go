@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+run() - запускает сервер и фоновые задачи и ждет отмены ctx (сигнала остановки).
+Затем по порядку: DrainGrace отвечает 503 на /readyz, продолжая обслуживать запросы,
+перестает принимать новые соединения, дожидается текущих запросов не дольше
+ShutdownTimeout и останавливает фоновые задачи. Пул соединений с БД закрывается после.
+Отдает код выхода процесса: 0 - штатная остановка, 1 - сервер упал
+или не успел дождаться запросов.
+*/
+func (ac *core) run(ctx context.Context) int {
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := ac.startWorkers(workersCtx)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- ac.echo.Start(ac.config.Addr)
+	}()
+	ac.log.Info("Server started", "addr", ac.config.Addr)
+
+	code := 0
+	select {
+	case err := <-serverErr:
+		ac.log.Error("Server stopped", "err", err)
+		code = 1
+	case <-ctx.Done():
+		// Сначала /readyz отвечает 503, чтобы балансировщик успел убрать сервер из ротации,
+		// и только потом сервер перестает принимать соединения
+		atomic.StoreInt32(&ac.draining, 1)
+		ac.log.Info("Shutting down, reporting not ready", "grace", ac.config.DrainGrace.Duration.String())
+		time.Sleep(ac.config.DrainGrace.Duration)
+
+		ac.log.Info("Waiting for in-flight requests")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ac.config.ShutdownTimeout.Duration)
+		defer cancel()
+
+		if err := ac.echo.Shutdown(shutdownCtx); err != nil {
+			ac.log.Error("Requests were not drained in time", "err", err)
+			ac.echo.Close()
+			code = 1
+		}
+	}
+
+	stopWorkers()
+	workers.Wait()
+	ac.log.Info("Background workers stopped")
+
+	return code
+}
+
+// startWorkers() - запускает фоновые задачи, они работают до отмены ctx
+func (ac *core) startWorkers(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ac.purgeExpired(ctx, ac.config.PurgeInterval.Duration)
+	}()
+
+	return &wg
+}
+
+// purgeExpired() - периодически удаляет истекшие отозванные токены доступа
+func (ac *core) purgeExpired(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ac.purgeOnce(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeOnce() - один проход очистки, ошибки только логируются: следующий проход повторит попытку
+func (ac *core) purgeOnce(ctx context.Context, now time.Time) {
+	n, err := ac.revocations.Purge(ctx, now)
+	if err != nil {
+		ac.log.Error("Revoked tokens purge failed", "err", err)
+	} else if n > 0 {
+		ac.log.Info("Expired revoked tokens purged", "count", n)
+	}
+}
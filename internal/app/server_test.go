@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunDrainsRequests(t *testing.T) {
+	tests := []struct {
+		addr     string
+		timeout  time.Duration
+		wantCode int
+		wantDone bool
+	}{
+		{ // in-flight request finishes before the deadline
+			"127.0.0.1:63247",
+			2 * time.Second,
+			0,
+			true,
+		},
+		{ // deadline is shorter than the request
+			"127.0.0.1:63248",
+			50 * time.Millisecond,
+			1,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		testCore := assembleTestCore()
+		testCore.config.Addr = tt.addr
+		testCore.config.ShutdownTimeout.Duration = tt.timeout
+		testCore.echo.GET("/test/slow", func(c echo.Context) error {
+			time.Sleep(500 * time.Millisecond)
+			return c.String(http.StatusOK, "done")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		exit := make(chan int)
+		go func() { exit <- testCore.run(ctx) }()
+		time.Sleep(300 * time.Millisecond)
+
+		body := make(chan string, 1)
+		go func() {
+			res, err := http.Get("http://" + tt.addr + "/test/slow")
+			if err != nil {
+				body <- ""
+				return
+			}
+			defer res.Body.Close()
+			b, _ := ioutil.ReadAll(res.Body)
+			body <- string(b)
+		}()
+		time.Sleep(100 * time.Millisecond)
+
+		cancel()
+		assert.Equal(t, tt.wantCode, <-exit)
+		assert.Equal(t, tt.wantDone, <-body == "done")
+
+		_, err := http.Get("http://" + tt.addr + "/test/alive")
+		assert.Error(t, err, "server must not accept connections after shutdown")
+	}
+}
+
+func TestRunDrainGrace(t *testing.T) {
+	const addr = "127.0.0.1:63249"
+
+	testCore := assembleTestCore()
+	testCore.config.Addr = addr
+	testCore.config.DrainGrace.Duration = 500 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exit := make(chan int)
+	go func() { exit <- testCore.run(ctx) }()
+	time.Sleep(300 * time.Millisecond)
+
+	res, err := http.Get("http://" + addr + "/readyz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	// Сервер еще принимает соединения, но балансировщику уже отвечает, что не готов
+	res, err = http.Get("http://" + addr + "/readyz")
+	if assert.NoError(t, err) {
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	}
+
+	assert.Equal(t, 0, <-exit)
+
+	_, err = http.Get("http://" + addr + "/readyz")
+	assert.Error(t, err, "server must not accept connections after shutdown")
+}
+
+func TestPurgeOnce(t *testing.T) {
+	testCore := assembleTestCore()
+	now := time.Now()
+
+	testCore.revocations.Revoke(context.Background(), "jti[alive]", now.Add(time.Minute))
+	testCore.revocations.Revoke(context.Background(), "jti[expired]", now.Add(-time.Minute))
+
+	testCore.purgeOnce(context.Background(), now)
+
+	n, err := testCore.revocations.Purge(context.Background(), now)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(0), n, "expired tokens must already be purged")
+	}
+
+	revoked, _ := testCore.revocations.IsRevoked(context.Background(), "jti[alive]", "uuid.v6[1]", now)
+	assert.True(t, revoked)
+}
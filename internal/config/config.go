@@ -0,0 +1,340 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/internal/i18n"
+	"github.com/JohanVong/online_bazaar/internal/logger"
+)
+
+// sslModes - допустимые значения sslmode для lib/pq
+var sslModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// Duration - промежуток времени, в JSON файле пишется строкой вида "15s"
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON() - читает промежуток в формате time.ParseDuration
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = v
+	return nil
+}
+
+// DBConfig - параметры подключения к постгрес
+type DBConfig struct {
+	Host             string
+	Port             int
+	User             string
+	Pass             string
+	Name             string
+	SSLMode          string
+	StatementTimeout Duration
+}
+
+/*
+Config - настройки приложения.
+Источники по возрастанию приоритета: значения по умолчанию, JSON файл,
+переменные окружения, флаги командной строки.
+*/
+type Config struct {
+	Addr            string
+	Sign            string
+	Lang            string
+	LogLevel        string
+	AutoMigrate     bool
+	ShutdownTimeout Duration
+	DrainGrace      Duration
+	RequestTimeout  Duration
+	PurgeInterval   Duration
+	ReadyTimeout    Duration
+	DB              DBConfig
+}
+
+// Default() - настройки по умолчанию
+func Default() *Config {
+	return &Config{
+		Addr:            ":8080",
+		Lang:            "en",
+		LogLevel:        "info",
+		ShutdownTimeout: Duration{15 * time.Second},
+		DrainGrace:      Duration{5 * time.Second},
+		RequestTimeout:  Duration{10 * time.Second},
+		PurgeInterval:   Duration{time.Hour},
+		ReadyTimeout:    Duration{2 * time.Second},
+		DB: DBConfig{
+			Host:             "localhost",
+			Port:             5432,
+			SSLMode:          "disable",
+			StatementTimeout: Duration{5 * time.Second},
+		},
+	}
+}
+
+/*
+Load() - собирает настройки из всех источников.
+Путь к файлу берется из флага -config или переменной CONFIG_FILE.
+Отдает также аргументы, оставшиеся после флагов (например, подкоманду migrate).
+*/
+func Load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("api", flag.ContinueOnError)
+	path := fs.String("config", "", "path to JSON config file")
+	addr := fs.String("addr", "", "address to listen on")
+	lang := fs.String("lang", "", "language of API messages when Accept-Language does not match")
+	logLevel := fs.String("log-level", "", "minimal log level: debug, info, warn or error")
+	autoMigrate := fs.Bool("auto-migrate", false, "apply migrations on startup")
+	requestTimeout := fs.Duration("request-timeout", 0, "how long a single request may take")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "how long to wait for in-flight requests on shutdown")
+	drainGrace := fs.Duration("drain-grace", 0, "how long to report not ready before shutdown starts")
+	host := fs.String("psql-host", "", "postgres host")
+	port := fs.Int("psql-port", 0, "postgres port")
+	user := fs.String("psql-user", "", "postgres user")
+	name := fs.String("psql-name", "", "postgres database name")
+	sslMode := fs.String("psql-sslmode", "", "postgres sslmode")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if !set["config"] {
+		*path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *path != "" {
+		if err = cfg.loadFile(*path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = cfg.loadEnv(lookupEnv); err != nil {
+		return nil, nil, err
+	}
+
+	for f := range set {
+		switch f {
+		case "addr":
+			cfg.Addr = *addr
+		case "lang":
+			cfg.Lang = *lang
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "auto-migrate":
+			cfg.AutoMigrate = *autoMigrate
+		case "request-timeout":
+			cfg.RequestTimeout.Duration = *requestTimeout
+		case "shutdown-timeout":
+			cfg.ShutdownTimeout.Duration = *shutdownTimeout
+		case "drain-grace":
+			cfg.DrainGrace.Duration = *drainGrace
+		case "psql-host":
+			cfg.DB.Host = *host
+		case "psql-port":
+			cfg.DB.Port = *port
+		case "psql-user":
+			cfg.DB.User = *user
+		case "psql-name":
+			cfg.DB.Name = *name
+		case "psql-sslmode":
+			cfg.DB.SSLMode = *sslMode
+		}
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// loadFile() - накладывает настройки из JSON файла, незнакомые поля считаются ошибкой
+func (cfg *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(cfg); err != nil {
+		return fmt.Errorf("Config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// loadEnv() - накладывает настройки из переменных окружения
+func (cfg *Config) loadEnv(lookupEnv func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"ADDR":         &cfg.Addr,
+		"SIGN":         &cfg.Sign,
+		"DEFAULT_LANG": &cfg.Lang,
+		"LOG_LEVEL":    &cfg.LogLevel,
+		"PSQL_HOST":    &cfg.DB.Host,
+		"PSQL_USER":    &cfg.DB.User,
+		"PSQL_PASS":    &cfg.DB.Pass,
+		"PSQL_NAME":    &cfg.DB.Name,
+		"PSQL_SSLMODE": &cfg.DB.SSLMode,
+	}
+	for key, dst := range strs {
+		if v, ok := lookupEnv(key); ok {
+			*dst = v
+		}
+	}
+
+	if v, ok := lookupEnv("PSQL_PORT"); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("PSQL_PORT: %w", err)
+		}
+		cfg.DB.Port = port
+	}
+
+	durations := map[string]*time.Duration{
+		"SHUTDOWN_TIMEOUT":       &cfg.ShutdownTimeout.Duration,
+		"DRAIN_GRACE":            &cfg.DrainGrace.Duration,
+		"REQUEST_TIMEOUT":        &cfg.RequestTimeout.Duration,
+		"PURGE_INTERVAL":         &cfg.PurgeInterval.Duration,
+		"READY_TIMEOUT":          &cfg.ReadyTimeout.Duration,
+		"PSQL_STATEMENT_TIMEOUT": &cfg.DB.StatementTimeout.Duration,
+	}
+	for key, dst := range durations {
+		if v, ok := lookupEnv(key); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			*dst = d
+		}
+	}
+
+	if v, ok := lookupEnv("AUTO_MIGRATE"); ok {
+		auto, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("AUTO_MIGRATE: %w", err)
+		}
+		cfg.AutoMigrate = auto
+	}
+
+	return nil
+}
+
+// Validate() - проверяет настройки, нужные для запуска сервера
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if cfg.Addr == "" {
+		problems = append(problems, "Addr is empty")
+	}
+	if cfg.Sign == "" {
+		problems = append(problems, "Sign is empty")
+	}
+	if !i18n.Supported(cfg.Lang) {
+		problems = append(problems, fmt.Sprintf("Lang %q is not supported", cfg.Lang))
+	}
+	if _, err := logger.ParseLevel(cfg.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("LogLevel %q is not supported", cfg.LogLevel))
+	}
+	if cfg.ShutdownTimeout.Duration <= 0 {
+		problems = append(problems, "ShutdownTimeout must be positive")
+	}
+	if cfg.DrainGrace.Duration < 0 {
+		problems = append(problems, "DrainGrace must not be negative")
+	}
+	if cfg.RequestTimeout.Duration <= 0 {
+		problems = append(problems, "RequestTimeout must be positive")
+	}
+	if cfg.PurgeInterval.Duration <= 0 {
+		problems = append(problems, "PurgeInterval must be positive")
+	}
+	if cfg.ReadyTimeout.Duration <= 0 {
+		problems = append(problems, "ReadyTimeout must be positive")
+	}
+	problems = append(problems, cfg.DB.problems()...)
+
+	return invalid(problems)
+}
+
+// Validate() - проверяет только параметры подключения к БД, их достаточно для миграций
+func (db *DBConfig) Validate() error {
+	return invalid(db.problems())
+}
+
+/*
+DSN() - строка подключения к постгрес для lib/pq.
+StatementTimeout уходит в постгрес параметром сессии statement_timeout,
+0 - без ограничения со стороны сервера БД.
+*/
+func (db *DBConfig) DSN() string {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quote(db.Host), db.Port, quote(db.User), quote(db.Pass), quote(db.Name), db.SSLMode)
+
+	if db.StatementTimeout.Duration > 0 {
+		dsn += fmt.Sprintf(" statement_timeout=%d", db.StatementTimeout.Milliseconds())
+	}
+
+	return dsn
+}
+
+func (db *DBConfig) problems() []string {
+	var problems []string
+
+	if db.Host == "" {
+		problems = append(problems, "DB.Host is empty")
+	}
+	if db.Port < 1 || db.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("DB.Port %d is out of range", db.Port))
+	}
+	if db.User == "" {
+		problems = append(problems, "DB.User is empty")
+	}
+	if db.Name == "" {
+		problems = append(problems, "DB.Name is empty")
+	}
+	if db.StatementTimeout.Duration < 0 {
+		problems = append(problems, "DB.StatementTimeout must not be negative")
+	}
+	if !sslModes[db.SSLMode] {
+		problems = append(problems, fmt.Sprintf("DB.SSLMode %q is not supported", db.SSLMode))
+	}
+
+	return problems
+}
+
+func invalid(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("Invalid config: %s", strings.Join(problems, "; "))
+}
+
+// quote() - экранирует значение для строки подключения, чтобы пробелы и кавычки не ломали ее
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `'`, `\'`)
+
+	return "'" + s + "'"
+}
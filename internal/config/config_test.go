@@ -0,0 +1,174 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// envOf() - подменяет окружение процесса заданным набором переменных
+func envOf(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := ioutil.WriteFile(path, []byte(`{"Addr":":9000","Sign":"FileSign","DB":{"Host":"db.file","User":"file"}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     []string
+		env      map[string]string
+		wantAddr string
+		wantSign string
+		wantHost string
+		wantUser string
+		wantPort int
+		wantArgs []string
+	}{
+		{ // defaults only
+			nil,
+			nil,
+			":8080",
+			"",
+			"localhost",
+			"",
+			5432,
+			nil,
+		},
+		{ // file over defaults
+			[]string{"-config", path},
+			nil,
+			":9000",
+			"FileSign",
+			"db.file",
+			"file",
+			5432,
+			nil,
+		},
+		{ // env over file, file taken from env
+			nil,
+			map[string]string{"CONFIG_FILE": path, "SIGN": "EnvSign", "PSQL_HOST": "db.env", "PSQL_PORT": "6432"},
+			":9000",
+			"EnvSign",
+			"db.env",
+			"file",
+			6432,
+			nil,
+		},
+		{ // flags over env, positional args are returned
+			[]string{"-config", path, "-psql-host", "db.flag", "-addr", ":7000", "migrate", "up"},
+			map[string]string{"PSQL_HOST": "db.env", "ADDR": ":6000"},
+			":7000",
+			"FileSign",
+			"db.flag",
+			"file",
+			5432,
+			[]string{"migrate", "up"},
+		},
+	}
+
+	for _, tt := range tests {
+		cfg, args, err := Load(tt.args, envOf(tt.env))
+		if assert.NoError(t, err) {
+			assert.Equal(t, tt.wantAddr, cfg.Addr)
+			assert.Equal(t, tt.wantSign, cfg.Sign)
+			assert.Equal(t, tt.wantHost, cfg.DB.Host)
+			assert.Equal(t, tt.wantUser, cfg.DB.User)
+			assert.Equal(t, tt.wantPort, cfg.DB.Port)
+			assert.Equal(t, len(tt.wantArgs), len(args))
+			assert.Subset(t, args, tt.wantArgs)
+		}
+	}
+
+	_, _, err = Load(nil, envOf(map[string]string{"PSQL_PORT": "port"}))
+	assert.Error(t, err)
+
+	_, _, err = Load([]string{"-config", filepath.Join(t.TempDir(), "missing.json")}, envOf(nil))
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := Default()
+		cfg.Sign = "TestSign"
+		cfg.DB.User = "user"
+		cfg.DB.Name = "bazaar"
+		return cfg
+	}
+
+	tests := []struct {
+		mutate  func(*Config)
+		wantErr string
+	}{
+		{ // valid config
+			func(*Config) {},
+			"",
+		},
+		{ // empty sign
+			func(cfg *Config) { cfg.Sign = "" },
+			"Invalid config: Sign is empty",
+		},
+		{ // language without a message catalog
+			func(cfg *Config) { cfg.Lang = "de" },
+			`Invalid config: Lang "de" is not supported`,
+		},
+		{ // unknown log level
+			func(cfg *Config) { cfg.LogLevel = "verbose" },
+			`Invalid config: LogLevel "verbose" is not supported`,
+		},
+		{ // purge worker without an interval
+			func(cfg *Config) { cfg.PurgeInterval.Duration = 0 },
+			"Invalid config: PurgeInterval must be positive",
+		},
+		{ // readiness checks without a deadline
+			func(cfg *Config) { cfg.ReadyTimeout.Duration = 0 },
+			"Invalid config: ReadyTimeout must be positive",
+		},
+		{ // no drain grace, shutdown starts right away
+			func(cfg *Config) { cfg.DrainGrace.Duration = 0 },
+			"",
+		},
+		{ // negative drain grace
+			func(cfg *Config) { cfg.DrainGrace.Duration = -time.Second },
+			"Invalid config: DrainGrace must not be negative",
+		},
+		{ // several problems at once
+			func(cfg *Config) { cfg.DB.Port = 0; cfg.DB.SSLMode = "sometimes" },
+			`Invalid config: DB.Port 0 is out of range; DB.SSLMode "sometimes" is not supported`,
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := valid()
+		tt.mutate(cfg)
+
+		err := cfg.Validate()
+		if tt.wantErr == "" {
+			assert.NoError(t, err)
+		} else if assert.Error(t, err) {
+			assert.Equal(t, tt.wantErr, err.Error())
+		}
+	}
+
+	cfg := valid()
+	cfg.Sign = ""
+	assert.NoError(t, cfg.DB.Validate(), "migrations do not need a sign")
+}
+
+func TestDSN(t *testing.T) {
+	db := &DBConfig{Host: "localhost", Port: 5432, User: "bazaar", Pass: `it's a secret`, Name: "bazaar", SSLMode: "disable"}
+
+	assert.Equal(t, `host='localhost' port=5432 user='bazaar' password='it\'s a secret' dbname='bazaar' sslmode=disable`, db.DSN())
+
+	db.StatementTimeout.Duration = 1500 * time.Millisecond
+	assert.Equal(t, `host='localhost' port=5432 user='bazaar' password='it\'s a secret' dbname='bazaar' sslmode=disable statement_timeout=1500`, db.DSN())
+}
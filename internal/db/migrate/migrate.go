@@ -0,0 +1,346 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/internal/db/migrations"
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+)
+
+// lockID - ключ advisory-блокировки, чтобы несколько экземпляров не мигрировали одновременно
+const lockID = 7310452
+
+// fileName - формат имени файла миграции: <версия>_<название>.<up|down>.sql
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration - одна миграция схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status - миграция и время ее применения, нулевое время - не применена
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+// Migrator - применяет и откатывает миграции, отслеживая их в таблице schema_migrations
+type Migrator struct {
+	DB *sql.DB
+	FS fs.FS
+}
+
+// New() - создает мигратор с миграциями, вшитыми в бинарник
+func New(db *sql.DB) *Migrator {
+	return &Migrator{DB: db, FS: migrations.FS}
+}
+
+// Load() - читает миграции из файловой системы мигратора, отсортированные по версии
+func (m *Migrator) Load() ([]*Migration, error) {
+	byVersion := make(map[int]*Migration)
+
+	entries, err := fs.ReadDir(m.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		body, err := fs.ReadFile(m.FS, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mg
+		}
+		if mg.Name != match[2] {
+			return nil, fmt.Errorf("Migration %d has different names: %s and %s", version, mg.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			mg.Up = string(body)
+		} else {
+			mg.Down = string(body)
+		}
+	}
+
+	list := make([]*Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" {
+			return nil, fmt.Errorf("Migration %d has no up file", mg.Version)
+		}
+		list = append(list, mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list, nil
+}
+
+// Latest() - версия последней известной бинарнику миграции
+func (m *Migrator) Latest() (int, error) {
+	list, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(list) == 0 {
+		return 0, nil
+	}
+
+	return list[len(list)-1].Version, nil
+}
+
+// Version() - текущая версия схемы в БД, 0 - ни одна миграция не применена
+func (m *Migrator) Version() (int, error) {
+	var version int
+
+	_, err := m.DB.Exec(stmts.CREATE_SCHEMA_MIGRATIONS)
+	if err != nil {
+		return 0, err
+	}
+
+	row := m.DB.QueryRow(stmts.GET_SCHEMA_VERSION)
+	err = row.Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+
+	return version, nil
+}
+
+// Status() - список всех миграций с отметкой о применении
+func (m *Migrator) Status() ([]*Status, error) {
+	list, err := m.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = m.DB.Exec(stmts.CREATE_SCHEMA_MIGRATIONS)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	rows, err := m.DB.Query(stmts.GET_MIGRATIONS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err = rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*Status, 0, len(list))
+	for _, mg := range list {
+		statuses = append(statuses, &Status{Version: mg.Version, Name: mg.Name, AppliedAt: applied[mg.Version]})
+	}
+
+	return statuses, nil
+}
+
+// Up() - применяет все еще не примененные миграции по порядку, отдает их количество
+func (m *Migrator) Up() (int, error) {
+	list, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = m.DB.Exec(stmts.CREATE_SCHEMA_MIGRATIONS)
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	for _, mg := range list {
+		done, err := m.apply(mg, true)
+		if err != nil {
+			return applied, fmt.Errorf("Migration %d_%s: %w", mg.Version, mg.Name, err)
+		}
+		if done {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// Down() - откатывает steps последних примененных миграций, отдает их количество
+func (m *Migrator) Down(steps int) (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	list, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	for i := len(list) - 1; i >= 0 && reverted < steps; i-- {
+		if statuses[i].AppliedAt.IsZero() {
+			continue
+		}
+
+		if list[i].Down == "" {
+			return reverted, fmt.Errorf("Migration %d_%s has no down file", list[i].Version, list[i].Name)
+		}
+
+		done, err := m.apply(list[i], false)
+		if err != nil {
+			return reverted, fmt.Errorf("Migration %d_%s: %w", list[i].Version, list[i].Name, err)
+		}
+		if done {
+			reverted++
+		}
+	}
+
+	return reverted, nil
+}
+
+/*
+Force() - отмечает миграции до version включительно примененными, а более поздние - нет,
+не выполняя их SQL. Нужна для баз, схема которых появилась до миграций: их помечают
+версией, которой схема соответствует, и дальше мигрируют как обычно. Отдает число
+миграций, отметка которых изменилась.
+*/
+func (m *Migrator) Force(version int) (int, error) {
+	var changed int
+
+	list, err := m.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	known := version == 0
+	for _, mg := range list {
+		known = known || mg.Version == version
+	}
+	if !known {
+		return 0, fmt.Errorf("Migration %d does not exist", version)
+	}
+
+	_, err = m.DB.Exec(stmts.CREATE_SCHEMA_MIGRATIONS)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(stmts.LOCK_MIGRATIONS, lockID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, mg := range list {
+		var res sql.Result
+		if mg.Version <= version {
+			res, err = tx.Exec(stmts.FORCE_MIGRATION, mg.Version, mg.Name, now)
+		} else {
+			res, err = tx.Exec(stmts.DELETE_MIGRATION, mg.Version)
+		}
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		n, _ := res.RowsAffected()
+		changed += int(n)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+/*
+apply() - применяет или откатывает одну миграцию в отдельной транзакции.
+Под advisory-блокировкой еще раз проверяется, что миграцию не применил
+параллельно запущенный экземпляр. Отдает false, если делать было нечего.
+*/
+func (m *Migrator) apply(mg *Migration, up bool) (bool, error) {
+	var done bool
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(stmts.LOCK_MIGRATIONS, lockID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	row := tx.QueryRow(stmts.IS_MIGRATION_DONE, mg.Version)
+	err = row.Scan(&done)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if done == up {
+		tx.Rollback()
+		return false, nil
+	}
+
+	if up {
+		_, err = tx.Exec(mg.Up)
+		if err == nil {
+			_, err = tx.Exec(stmts.INSERT_MIGRATION, mg.Version, mg.Name, time.Now())
+		}
+	} else {
+		_, err = tx.Exec(mg.Down)
+		if err == nil {
+			_, err = tx.Exec(stmts.DELETE_MIGRATION, mg.Version)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
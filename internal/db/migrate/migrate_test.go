@@ -0,0 +1,87 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		files       fstest.MapFS
+		wantVersion []int
+		wantErr     bool
+	}{
+		{ // sorted numerically, not lexically
+			fstest.MapFS{
+				"10_ten.up.sql":   {Data: []byte("10 up")},
+				"10_ten.down.sql": {Data: []byte("10 down")},
+				"2_two.up.sql":    {Data: []byte("2 up")},
+				"1_one.up.sql":    {Data: []byte("1 up")},
+				"1_one.down.sql":  {Data: []byte("1 down")},
+				"readme.md":       {Data: []byte("not a migration")},
+			},
+			[]int{1, 2, 10},
+			false,
+		},
+		{ // down without up
+			fstest.MapFS{
+				"1_one.down.sql": {Data: []byte("1 down")},
+			},
+			nil,
+			true,
+		},
+		{ // same version, different names
+			fstest.MapFS{
+				"1_one.up.sql":   {Data: []byte("1 up")},
+				"1_uno.down.sql": {Data: []byte("1 down")},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		list, err := (&Migrator{FS: tt.files}).Load()
+		if tt.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+
+		if assert.NoError(t, err) {
+			versions := make([]int, 0, len(list))
+			for _, mg := range list {
+				versions = append(versions, mg.Version)
+			}
+			assert.Equal(t, tt.wantVersion, versions)
+			assert.Equal(t, "10 down", list[2].Down)
+			assert.Equal(t, "", list[1].Down)
+		}
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	m := New(nil)
+
+	list, err := m.Load()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i, mg := range list {
+		assert.Equal(t, i+1, mg.Version, "migration versions must have no gaps")
+		assert.NotEmpty(t, mg.Down, "migration %d has no down file", mg.Version)
+	}
+
+	latest, err := m.Latest()
+	if assert.NoError(t, err) {
+		assert.Equal(t, len(list), latest)
+	}
+}
+
+func TestForceUnknownVersion(t *testing.T) {
+	// До обращения к БД проверяется, что такая миграция есть
+	_, err := New(nil).Force(99)
+	assert.EqualError(t, err, "Migration 99 does not exist")
+}
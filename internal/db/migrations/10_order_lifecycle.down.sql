@@ -0,0 +1,19 @@
+DROP TABLE order_transitions;
+
+ALTER TABLE orders ALTER COLUMN status_uid DROP NOT NULL;
+
+-- Заказы в статусах, которых до этой миграции не было, возвращаются в created
+UPDATE orders SET status_uid = '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a01' WHERE status_uid IN (
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a02',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a03',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a04',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a05',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a06'
+);
+DELETE FROM statuses WHERE status_uid IN (
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a02',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a03',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a04',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a05',
+    '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a06'
+);
@@ -0,0 +1,21 @@
+INSERT INTO statuses (status_uid, status) VALUES
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a02', 'paid'),
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a03', 'shipped'),
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a04', 'delivered'),
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a05', 'cancelled'),
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a06', 'refunded')
+ON CONFLICT (status) DO NOTHING;
+
+UPDATE orders SET status_uid = (SELECT status_uid FROM statuses WHERE status = 'created') WHERE status_uid IS NULL;
+ALTER TABLE orders ALTER COLUMN status_uid SET NOT NULL;
+
+CREATE TABLE order_transitions (
+    transition_uid uuid NOT NULL PRIMARY KEY,
+    order_uid uuid NOT NULL REFERENCES orders(order_uid),
+    from_status_uid uuid REFERENCES statuses(status_uid),
+    to_status_uid uuid NOT NULL REFERENCES statuses(status_uid),
+    actor_uid uuid NOT NULL REFERENCES users(user_uid),
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX order_transitions_order_idx ON order_transitions (order_uid);
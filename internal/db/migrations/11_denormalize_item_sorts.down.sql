@@ -0,0 +1,8 @@
+DROP INDEX items_rating_idx;
+DROP INDEX items_created_idx;
+
+DROP TRIGGER item_ratings_refresh ON item_ratings;
+DROP FUNCTION refresh_item_rating();
+
+ALTER TABLE items DROP COLUMN rating;
+ALTER TABLE items DROP COLUMN created_at;
@@ -0,0 +1,30 @@
+-- Колонки сортировок поиска переезжают в items, чтобы ORDER BY шел по индексу,
+-- а не по агрегату над всей item_ratings и не по histories
+ALTER TABLE items ADD COLUMN created_at timestamp;
+ALTER TABLE items ADD COLUMN rating numeric(8, 2) NOT NULL DEFAULT 0;
+
+UPDATE items i SET created_at = h.created_at FROM histories h WHERE h.history_uid = i.history_uid;
+UPDATE items i SET rating = r.rating
+FROM (SELECT item_uid, ROUND(avg(mark), 2) AS rating FROM item_ratings GROUP BY item_uid) r
+WHERE r.item_uid = i.item_uid;
+
+ALTER TABLE items ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE items ALTER COLUMN created_at SET DEFAULT now();
+
+-- Средняя оценка пересчитывается для товара при любой записи в item_ratings
+CREATE FUNCTION refresh_item_rating() RETURNS trigger AS $$
+BEGIN
+    UPDATE items SET rating = COALESCE(
+        (SELECT ROUND(avg(mark), 2) FROM item_ratings WHERE item_uid = items.item_uid), 0
+    )
+    WHERE item_uid IN (OLD.item_uid, NEW.item_uid);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER item_ratings_refresh
+    AFTER INSERT OR UPDATE OR DELETE ON item_ratings
+    FOR EACH ROW EXECUTE FUNCTION refresh_item_rating();
+
+CREATE INDEX items_created_idx ON items (created_at, item_uid);
+CREATE INDEX items_rating_idx ON items (rating, item_uid);
@@ -0,0 +1 @@
+ALTER TABLE items DROP COLUMN measure_unit_id;
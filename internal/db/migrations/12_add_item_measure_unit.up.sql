@@ -0,0 +1,12 @@
+-- Единица измерения задается продавцом на товаре, а не покупателем в строке корзины
+ALTER TABLE items ADD COLUMN measure_unit_id uuid REFERENCES measure_units(mu_uid);
+UPDATE items SET measure_unit_id = (SELECT mu_uid FROM measure_units WHERE unit = 'pcs');
+ALTER TABLE items ALTER COLUMN measure_unit_id SET NOT NULL;
+
+-- Строки корзин переводятся в единицу товара, дробное количество штучного товара округляется вверх
+UPDATE cart_items c SET
+    measure_unit_id = i.measure_unit_id,
+    quantity = CASE WHEN mu.fractional THEN c.quantity ELSE CEIL(c.quantity) END
+FROM items i
+JOIN measure_units mu ON mu.mu_uid = i.measure_unit_id
+WHERE i.item_uid = c.item_uid;
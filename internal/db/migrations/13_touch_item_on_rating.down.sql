@@ -0,0 +1,9 @@
+CREATE OR REPLACE FUNCTION refresh_item_rating() RETURNS trigger AS $$
+BEGIN
+    UPDATE items SET rating = COALESCE(
+        (SELECT ROUND(avg(mark), 2) FROM item_ratings WHERE item_uid = items.item_uid), 0
+    )
+    WHERE item_uid IN (OLD.item_uid, NEW.item_uid);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,15 @@
+-- Средняя оценка - часть товара, поэтому ее пересчет меняет и версию товара (ETag)
+CREATE OR REPLACE FUNCTION refresh_item_rating() RETURNS trigger AS $$
+BEGIN
+    UPDATE items SET rating = COALESCE(
+        (SELECT ROUND(avg(mark), 2) FROM item_ratings WHERE item_uid = items.item_uid), 0
+    )
+    WHERE item_uid IN (OLD.item_uid, NEW.item_uid);
+
+    UPDATE histories h SET updated_at = now()::timestamp
+    FROM items i
+    WHERE i.item_uid IN (OLD.item_uid, NEW.item_uid) AND h.history_uid = i.history_uid;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1 @@
+ALTER TABLE items ALTER COLUMN in_stock TYPE int USING FLOOR(in_stock)::int;
@@ -0,0 +1,3 @@
+-- Остаток ведется с той же точностью, что и количество в корзине и заказе,
+-- чтобы весовой товар списывался и оплачивался ровно в заказанном количестве
+ALTER TABLE items ALTER COLUMN in_stock TYPE numeric(12,2);
@@ -0,0 +1,11 @@
+DROP TABLE order_to_item;
+DROP TABLE orders;
+DROP TABLE item_ratings;
+DROP TABLE shop_ratings;
+DROP TABLE items;
+DROP TABLE shops;
+DROP TABLE users;
+DROP TABLE countries;
+DROP TABLE measure_units;
+DROP TABLE statuses;
+DROP TABLE histories;
@@ -0,0 +1,13 @@
+CREATE TABLE refresh_tokens (
+    token_uid uuid NOT NULL PRIMARY KEY,
+    family_uid uuid NOT NULL,
+    user_uid uuid NOT NULL REFERENCES users(user_uid),
+    token_hash varchar(64) NOT NULL,
+    created_at timestamp DEFAULT now(),
+    expires_at timestamp NOT NULL,
+    used_at timestamp,
+    revoked_at timestamp,
+    UNIQUE(token_hash)
+);
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_uid);
@@ -0,0 +1,9 @@
+CREATE TABLE revoked_tokens (
+    jti uuid NOT NULL PRIMARY KEY,
+    expires_at timestamp NOT NULL
+);
+
+CREATE TABLE user_revocations (
+    user_uid uuid NOT NULL PRIMARY KEY REFERENCES users(user_uid),
+    revoked_before timestamp NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE user_to_role;
+DROP TABLE roles;
@@ -0,0 +1,20 @@
+CREATE TABLE roles (
+    role_uid uuid NOT NULL PRIMARY KEY,
+    role varchar(30) NOT NULL,
+    UNIQUE(role)
+);
+
+CREATE TABLE user_to_role (
+    user_uid uuid NOT NULL REFERENCES users(user_uid),
+    role_uid uuid NOT NULL REFERENCES roles(role_uid),
+    PRIMARY KEY (user_uid, role_uid)
+);
+
+INSERT INTO roles (role_uid, role) VALUES
+    ('1edd4a1c-5e0b-6a3e-9b1f-2f6a8c1d0e01', 'buyer'),
+    ('1edd4a1c-5e0b-6a3e-9b1f-2f6a8c1d0e02', 'seller'),
+    ('1edd4a1c-5e0b-6a3e-9b1f-2f6a8c1d0e03', 'admin');
+
+-- Все уже зарегистрированные пользователи становятся покупателями
+INSERT INTO user_to_role (user_uid, role_uid)
+    SELECT user_uid, '1edd4a1c-5e0b-6a3e-9b1f-2f6a8c1d0e01' FROM users;
@@ -0,0 +1,3 @@
+DROP INDEX shops_user_idx;
+
+ALTER TABLE shops DROP COLUMN user_uid;
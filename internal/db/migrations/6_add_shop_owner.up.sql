@@ -0,0 +1,3 @@
+ALTER TABLE shops ADD COLUMN user_uid uuid REFERENCES users(user_uid);
+
+CREATE INDEX shops_user_idx ON shops (user_uid);
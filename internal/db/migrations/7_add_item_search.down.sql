@@ -0,0 +1,6 @@
+DROP INDEX item_ratings_item_idx;
+DROP INDEX items_shop_idx;
+DROP INDEX items_price_idx;
+DROP INDEX items_search_idx;
+
+ALTER TABLE items DROP COLUMN search;
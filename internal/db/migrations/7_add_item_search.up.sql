@@ -0,0 +1,9 @@
+ALTER TABLE items ADD COLUMN search tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', name || ' ' || vendor || ' ' || COALESCE(description, ''))
+    ) STORED;
+
+CREATE INDEX items_search_idx ON items USING GIN (search);
+CREATE INDEX items_price_idx ON items (price, item_uid);
+CREATE INDEX items_shop_idx ON items (shop_uid);
+CREATE INDEX item_ratings_item_idx ON item_ratings (item_uid);
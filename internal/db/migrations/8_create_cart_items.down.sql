@@ -0,0 +1,10 @@
+DROP TABLE cart_items;
+
+DELETE FROM measure_units WHERE mu_uid IN (
+    '1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f01',
+    '1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f02',
+    '1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f03',
+    '1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f04'
+);
+
+ALTER TABLE measure_units DROP COLUMN fractional;
@@ -0,0 +1,16 @@
+ALTER TABLE measure_units ADD COLUMN fractional boolean NOT NULL DEFAULT false;
+
+INSERT INTO measure_units (mu_uid, unit, fractional) VALUES
+    ('1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f01', 'pcs', false),
+    ('1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f02', 'kg', true),
+    ('1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f03', 'l', true),
+    ('1edd5b2e-7c41-6f0a-8d3e-5a1b9c7e4f04', 'm', true);
+
+CREATE TABLE cart_items (
+    user_uid uuid NOT NULL REFERENCES users(user_uid),
+    item_uid uuid NOT NULL REFERENCES items(item_uid),
+    quantity numeric(10, 2) NOT NULL CHECK (quantity > 0),
+    measure_unit_id uuid NOT NULL REFERENCES measure_units(mu_uid),
+    added_at timestamp DEFAULT now(),
+    PRIMARY KEY (user_uid, item_uid)
+);
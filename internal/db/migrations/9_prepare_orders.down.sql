@@ -0,0 +1,7 @@
+DROP INDEX orders_user_idx;
+DROP INDEX order_to_item_order_idx;
+
+ALTER TABLE order_to_item DROP COLUMN price;
+
+UPDATE orders SET status_uid = NULL WHERE status_uid = '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a01';
+DELETE FROM statuses WHERE status_uid = '1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a01';
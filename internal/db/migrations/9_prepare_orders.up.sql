@@ -0,0 +1,8 @@
+INSERT INTO statuses (status_uid, status) VALUES
+    ('1edd6c3f-8d52-6a1b-9e4f-6b2c0d8f5a01', 'created');
+
+-- Цена фиксируется в момент оформления, чтобы заказ не менялся вместе с ценой товара
+ALTER TABLE order_to_item ADD COLUMN price numeric(19, 2) CHECK (price > 0);
+
+CREATE INDEX order_to_item_order_idx ON order_to_item (order_uid);
+CREATE INDEX orders_user_idx ON orders (user_uid);
@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS - миграции, вшитые в бинарник. Файлы называются <версия>_<название>.up.sql
+// и <версия>_<название>.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,33 @@
+package stmts
+
+const (
+	GET_CART = `
+	SELECT 
+		c.item_uid, 
+		i.shop_uid, 
+		i.name, 
+		i.vendor, 
+		i.price, 
+		c.quantity, 
+		mu.unit, 
+		i.in_stock, 
+		h.deleted_at IS NULL AND sh.deleted_at IS NULL AS active
+	FROM cart_items c
+	JOIN items i ON i.item_uid = c.item_uid
+	JOIN histories h ON h.history_uid = i.history_uid
+	JOIN shops s ON s.shop_uid = i.shop_uid
+	JOIN histories sh ON sh.history_uid = s.history_uid
+	JOIN measure_units mu ON mu.mu_uid = c.measure_unit_id
+	WHERE c.user_uid = $1
+	ORDER BY c.added_at, c.item_uid;`
+
+	PUT_CART_ITEM = `
+	INSERT INTO cart_items (user_uid, item_uid, quantity, measure_unit_id) VALUES ($1, $2, $3, $4) 
+	ON CONFLICT (user_uid, item_uid) DO UPDATE SET quantity = EXCLUDED.quantity, measure_unit_id = EXCLUDED.measure_unit_id;`
+	// ADD_CART_ITEM - как PUT_CART_ITEM, но прибавляет количество к уже лежащему в корзине
+	ADD_CART_ITEM = `
+	INSERT INTO cart_items (user_uid, item_uid, quantity, measure_unit_id) VALUES ($1, $2, $3, $4) 
+	ON CONFLICT (user_uid, item_uid) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, measure_unit_id = EXCLUDED.measure_unit_id;`
+	REMOVE_CART_ITEM = "DELETE FROM cart_items WHERE user_uid = $1 AND item_uid = $2;"
+	CLEAR_CART       = "DELETE FROM cart_items WHERE user_uid = $1;"
+)
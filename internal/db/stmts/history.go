@@ -3,5 +3,9 @@ package stmts
 const (
 	INSERT_HISTORY = "INSERT INTO histories (history_uid, created_at, updated_at, deleted_at) VALUES ($1, $2, $3, $4);"
 	UPDATE_HISTORY = "UPDATE histories SET updated_at = $1 WHERE history_uid = $2;"
-	DELETE_HISTORY = "UPDATE histories SET deleted_at = $1 WHERE history_uid = $2;"
+	// UPDATE_HISTORY_IF_VERSION - то же, что UPDATE_HISTORY, но только если версия записи одна из $3
+	UPDATE_HISTORY_IF_VERSION = "UPDATE histories SET updated_at = $1 WHERE history_uid = $2 AND COALESCE(updated_at, created_at) = ANY($3::timestamp[]);"
+	DELETE_HISTORY            = "UPDATE histories SET deleted_at = $1 WHERE history_uid = $2;"
+	// DELETE_HISTORY_IF_VERSION - то же, что DELETE_HISTORY, но только если версия записи одна из $3
+	DELETE_HISTORY_IF_VERSION = "UPDATE histories SET deleted_at = $1 WHERE history_uid = $2 AND COALESCE(updated_at, created_at) = ANY($3::timestamp[]);"
 )
@@ -0,0 +1,66 @@
+package stmts
+
+const (
+	get_item = `
+	SELECT 
+		i.item_uid, 
+		i.shop_uid, 
+		COALESCE (s.user_uid::text, '') AS user_uid,
+		i.name, 
+		i.vendor, 
+		i.price, 
+		COALESCE (i.description, '') AS description,
+		i.in_stock, 
+		mu.unit,
+		i.history_uid,
+		h.created_at, 
+		COALESCE (h.updated_at, '0001-01-01') AS updated_at, 
+		COALESCE (h.deleted_at, '0001-01-01') AS deleted_at
+	FROM items i
+	JOIN shops s ON s.shop_uid = i.shop_uid
+	JOIN histories h ON h.history_uid = i.history_uid
+	JOIN measure_units mu ON mu.mu_uid = i.measure_unit_id`
+	GET_ITEM_BY_PK = get_item + " WHERE i.item_uid = $1;"
+	// У удаленного магазина товаров в списке нет, как и в поиске
+	GET_ITEMS_BY_SHOP = get_item + `
+	JOIN histories sh ON sh.history_uid = s.history_uid
+	WHERE i.shop_uid = $1 AND h.deleted_at IS NULL AND sh.deleted_at IS NULL
+	ORDER BY i.name;`
+
+	INSERT_ITEM  = "INSERT INTO items (item_uid, name, vendor, price, description, in_stock, shop_uid, history_uid, created_at, measure_unit_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, (SELECT mu_uid FROM measure_units WHERE unit = $10));"
+	RESTOCK_ITEM = "UPDATE items SET in_stock = in_stock + $1 WHERE item_uid = $2;"
+)
+
+/*
+SEARCH_ITEMS - выборка для поиска товаров. Фильтры подставляются вместо %s
+внутри подзапроса, условие курсора, сортировка и лимит - после него.
+Дата создания и средняя оценка хранятся в самих items (их поддерживает триггер
+на item_ratings), поэтому каждой сортировке соответствует индекс (колонка, item_uid).
+*/
+const SEARCH_ITEMS = `
+	SELECT 
+		item_uid, shop_uid, user_uid, name, vendor, price, description, in_stock, 
+		measure_unit, history_uid, created_at, updated_at, deleted_at, rating
+	FROM (
+		SELECT 
+			i.item_uid, 
+			i.shop_uid, 
+			COALESCE (s.user_uid::text, '') AS user_uid,
+			i.name, 
+			i.vendor, 
+			i.price, 
+			COALESCE (i.description, '') AS description,
+			i.in_stock, 
+			mu.unit AS measure_unit,
+			i.history_uid,
+			i.created_at, 
+			COALESCE (h.updated_at, '0001-01-01') AS updated_at, 
+			'0001-01-01'::timestamp AS deleted_at,
+			i.rating
+		FROM items i
+		JOIN histories h ON h.history_uid = i.history_uid
+		JOIN shops s ON s.shop_uid = i.shop_uid
+		JOIN histories sh ON sh.history_uid = s.history_uid
+		JOIN measure_units mu ON mu.mu_uid = i.measure_unit_id
+		WHERE h.deleted_at IS NULL AND sh.deleted_at IS NULL%s
+	) found`
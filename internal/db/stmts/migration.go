@@ -0,0 +1,18 @@
+package stmts
+
+const (
+	CREATE_SCHEMA_MIGRATIONS = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version int NOT NULL PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		applied_at timestamp NOT NULL DEFAULT now()
+	);`
+	LOCK_MIGRATIONS = "SELECT pg_advisory_xact_lock($1);"
+
+	GET_MIGRATIONS     = "SELECT version, applied_at FROM schema_migrations ORDER BY version;"
+	GET_SCHEMA_VERSION = "SELECT COALESCE (MAX(version), 0) FROM schema_migrations;"
+	IS_MIGRATION_DONE  = "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1);"
+	INSERT_MIGRATION   = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);"
+	DELETE_MIGRATION   = "DELETE FROM schema_migrations WHERE version = $1;"
+	FORCE_MIGRATION    = "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3) ON CONFLICT (version) DO NOTHING;"
+)
@@ -0,0 +1,88 @@
+package stmts
+
+const (
+	LOCK_CART = `
+	SELECT item_uid, quantity, measure_unit_id 
+	FROM cart_items 
+	WHERE user_uid = $1 
+	ORDER BY item_uid 
+	FOR UPDATE;`
+	LOCK_ITEM = `
+	SELECT 
+		i.name, 
+		i.price, 
+		i.in_stock, 
+		i.history_uid, 
+		i.measure_unit_id, 
+		mu.fractional, 
+		h.deleted_at IS NULL AND sh.deleted_at IS NULL AS active
+	FROM items i
+	JOIN histories h ON h.history_uid = i.history_uid
+	JOIN shops s ON s.shop_uid = i.shop_uid
+	JOIN histories sh ON sh.history_uid = s.history_uid
+	JOIN measure_units mu ON mu.mu_uid = i.measure_unit_id
+	WHERE i.item_uid = $1
+	FOR UPDATE OF i;`
+	TAKE_FROM_STOCK = "UPDATE items SET in_stock = in_stock - $1 WHERE item_uid = $2;"
+
+	INSERT_ORDER      = "INSERT INTO orders (order_uid, user_uid, status_uid, history_uid) SELECT $1, $2, status_uid, $3 FROM statuses WHERE status = $4;"
+	INSERT_ORDER_LINE = "INSERT INTO order_to_item (oti_uid, order_uid, item_uid, quantity, measure_unit_id, price) VALUES ($1, $2, $3, $4, $5, $6);"
+)
+
+const (
+	get_order = `
+	SELECT 
+		o.order_uid, 
+		COALESCE (o.user_uid::text, '') AS user_uid, 
+		s.status, 
+		o.history_uid,
+		h.created_at, 
+		COALESCE (h.updated_at, '0001-01-01') AS updated_at,
+		COALESCE ((
+			SELECT SUM(ROUND(oti.quantity * oti.price, 2)) FROM order_to_item oti WHERE oti.order_uid = o.order_uid
+		), 0) AS total
+	FROM orders o
+	JOIN statuses s ON s.status_uid = o.status_uid
+	JOIN histories h ON h.history_uid = o.history_uid`
+	GET_ORDER_BY_PK    = get_order + " WHERE o.order_uid = $1;"
+	GET_ORDERS_BY_USER = get_order + " WHERE o.user_uid = $1 ORDER BY h.created_at DESC;"
+
+	GET_ORDER_LINES = `
+	SELECT 
+		oti.item_uid, 
+		i.shop_uid, 
+		COALESCE (sh.user_uid::text, '') AS user_uid, 
+		i.name, 
+		oti.quantity, 
+		COALESCE (mu.unit, '') AS unit, 
+		oti.price
+	FROM order_to_item oti
+	JOIN items i ON i.item_uid = oti.item_uid
+	JOIN shops sh ON sh.shop_uid = i.shop_uid
+	LEFT JOIN measure_units mu ON mu.mu_uid = oti.measure_unit_id
+	WHERE oti.order_uid = $1
+	ORDER BY i.name, oti.item_uid;`
+	GET_ORDER_TRANSITIONS = `
+	SELECT 
+		COALESCE (f.status, '') AS from_status, 
+		t.status AS to_status, 
+		ot.actor_uid, 
+		ot.created_at
+	FROM order_transitions ot
+	LEFT JOIN statuses f ON f.status_uid = ot.from_status_uid
+	JOIN statuses t ON t.status_uid = ot.to_status_uid
+	WHERE ot.order_uid = $1
+	ORDER BY ot.created_at, ot.transition_uid;`
+
+	LOCK_ORDER = `
+	SELECT s.status, o.history_uid 
+	FROM orders o 
+	JOIN statuses s ON s.status_uid = o.status_uid 
+	WHERE o.order_uid = $1 
+	FOR UPDATE OF o;`
+	SET_ORDER_STATUS        = "UPDATE orders SET status_uid = (SELECT status_uid FROM statuses WHERE status = $1) WHERE order_uid = $2;"
+	INSERT_ORDER_TRANSITION = "INSERT INTO order_transitions (transition_uid, order_uid, from_status_uid, to_status_uid, actor_uid, created_at) SELECT $1, $2, (SELECT status_uid FROM statuses WHERE status = $3), status_uid, $4, $5 FROM statuses WHERE status = $6;"
+	RETURN_ORDER_TO_STOCK   = "UPDATE items i SET in_stock = i.in_stock + oti.quantity FROM order_to_item oti WHERE oti.order_uid = $1 AND oti.item_uid = i.item_uid;"
+	// TOUCH_ORDER_ITEMS - отмечает изменение товаров заказа $2 в их историях, чтобы сменились их ETag
+	TOUCH_ORDER_ITEMS = "UPDATE histories h SET updated_at = $1 FROM items i JOIN order_to_item oti ON oti.item_uid = i.item_uid WHERE oti.order_uid = $2 AND h.history_uid = i.history_uid;"
+)
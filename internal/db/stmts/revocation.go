@@ -0,0 +1,11 @@
+package stmts
+
+const (
+	INSERT_REVOKED_TOKEN = "INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING;"
+	PURGE_REVOKED_TOKENS = "DELETE FROM revoked_tokens WHERE expires_at < $1;"
+	UPSERT_USER_REVOKE   = "INSERT INTO user_revocations (user_uid, revoked_before) VALUES ($1, $2) ON CONFLICT (user_uid) DO UPDATE SET revoked_before = EXCLUDED.revoked_before;"
+	IS_TOKEN_REVOKED     = `
+	SELECT 
+		EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at >= $4) 
+		OR EXISTS (SELECT 1 FROM user_revocations WHERE user_uid = $2 AND revoked_before >= $3);`
+)
@@ -0,0 +1,7 @@
+package stmts
+
+const (
+	GET_ROLE_PK      = "SELECT role_uid FROM roles WHERE role = $1;"
+	GRANT_USER_ROLE  = "INSERT INTO user_to_role (user_uid, role_uid) VALUES ($1, $2) ON CONFLICT DO NOTHING;"
+	REVOKE_USER_ROLE = "DELETE FROM user_to_role WHERE user_uid = $1 AND role_uid = $2;"
+)
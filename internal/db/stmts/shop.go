@@ -0,0 +1,21 @@
+package stmts
+
+const (
+	get_shop = `
+	SELECT 
+		shop_uid, 
+		name, 
+		COALESCE (description, '') AS description,
+		COALESCE (user_uid::text, '') AS user_uid,
+		history_uid,
+		created_at, 
+		COALESCE (updated_at, '0001-01-01') AS updated_at, 
+		COALESCE (deleted_at, '0001-01-01') AS deleted_at
+	FROM shops 
+	JOIN histories USING (history_uid)`
+	GET_SHOP_BY_PK   = get_shop + " WHERE shop_uid = $1;"
+	GET_SHOPS        = get_shop + " WHERE deleted_at IS NULL ORDER BY name;"
+	GET_SHOPS_BY_OWN = get_shop + " WHERE deleted_at IS NULL AND user_uid = $1 ORDER BY name;"
+
+	INSERT_SHOP = "INSERT INTO shops (shop_uid, name, description, user_uid, history_uid) VALUES ($1, $2, $3, $4, $5);"
+)
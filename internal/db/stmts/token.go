@@ -0,0 +1,19 @@
+package stmts
+
+const (
+	GET_REFRESH_TOKEN = `
+	SELECT 
+		token_uid, 
+		family_uid, 
+		user_uid, 
+		expires_at, 
+		COALESCE (used_at, '0001-01-01') AS used_at, 
+		COALESCE (revoked_at, '0001-01-01') AS revoked_at
+	FROM refresh_tokens 
+	WHERE token_hash = $1;`
+
+	INSERT_REFRESH_TOKEN = "INSERT INTO refresh_tokens (token_uid, family_uid, user_uid, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6);"
+	USE_REFRESH_TOKEN    = "UPDATE refresh_tokens SET used_at = $1 WHERE token_uid = $2 AND used_at IS NULL AND revoked_at IS NULL;"
+	REVOKE_TOKEN_FAMILY  = "UPDATE refresh_tokens SET revoked_at = $1 WHERE family_uid = $2 AND revoked_at IS NULL;"
+	REVOKE_USER_TOKENS   = "UPDATE refresh_tokens SET revoked_at = $1 WHERE user_uid = $2 AND revoked_at IS NULL;"
+)
@@ -0,0 +1,6 @@
+package stmts
+
+const (
+	GET_UNIT_BY_NAME = "SELECT mu_uid, unit, fractional FROM measure_units WHERE unit = $1;"
+	GET_UNITS        = "SELECT mu_uid, unit, fractional FROM measure_units ORDER BY unit;"
+)
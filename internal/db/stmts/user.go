@@ -13,7 +13,11 @@ const (
 		history_uid,
 		created_at, 
 		COALESCE (updated_at, '0001-01-01') AS updated_at, 
-		COALESCE (deleted_at, '0001-01-01') AS deleted_at
+		COALESCE (deleted_at, '0001-01-01') AS deleted_at,
+		ARRAY (
+			SELECT role FROM user_to_role JOIN roles USING (role_uid) 
+			WHERE user_to_role.user_uid = users.user_uid ORDER BY role
+		) AS roles
 	FROM users 
 	JOIN countries USING (country_uid) 
 	JOIN histories USING (history_uid)`
@@ -21,4 +25,8 @@ const (
 	GET_USER_BY_PK   = get_user + " WHERE user_uid = $1;"
 
 	INSERT_USER = "INSERT INTO users (user_uid, username, pw_hash, email, phone, country_uid, history_uid) VALUES ($1, $2, $3, $4, $5, $6, $7);"
+	// UPDATE_USER - шаблон для fmt.Sprintf: список "колонка = $n" и номер параметра с user_uid
+	UPDATE_USER         = "UPDATE users SET %s WHERE user_uid = $%d RETURNING history_uid;"
+	UPDATE_USER_HISTORY = "UPDATE histories SET updated_at = $1 WHERE history_uid = (SELECT history_uid FROM users WHERE user_uid = $2);"
+	UPDATE_USER_HASH    = "UPDATE users SET pw_hash = $1 WHERE user_uid = $2;"
 )
@@ -0,0 +1,67 @@
+package i18n
+
+// messagesEN - сообщения API на английском, {0} - имя поля, {1} - параметр правила
+var messagesEN = map[string]string{
+	// Ответы приложения
+	"validation_failed": "Data validation failed",
+	"bad_format":        "Wrong data format",
+	"internal":          "Internal server error",
+	"timeout":           "Request timed out",
+	"cancelled":         "Request was cancelled",
+
+	// Общие доменные ошибки
+	"not_found":         "Record not found",
+	"already_exists":    "Record already exists",
+	"bad_reference":     "Referenced record does not exist",
+	"bad_identifier":    "Malformed identifier",
+	"nothing_to_update": "Nothing to update",
+	"access_denied":     "Access denied",
+	"version_mismatch":  "Record was changed since it was read",
+
+	// Пользователи, роли и токены
+	"user_not_found":        "User not found",
+	"user_deleted":          "User was deleted",
+	"user_exists":           "Username or email is already taken",
+	"wrong_credentials":     "Wrong credentials provided",
+	"unknown_country":       "Provided country does not exist",
+	"unknown_role":          "Provided role does not exist",
+	"own_admin_role":        "Admin can not revoke own admin role",
+	"token_reused":          "Refresh token reuse detected",
+	"invalid_refresh_token": "Invalid refresh token",
+	"refresh_token_expired": "Refresh token expired",
+	"invalid_token":         "Invalid token",
+	"token_revoked":         "Token was revoked",
+
+	// Магазины и товары
+	"shop_not_found":       "Shop not found",
+	"shop_deleted":         "Shop was deleted",
+	"shop_exists":          "Shop name is already taken",
+	"item_not_found":       "Item not found",
+	"item_deleted":         "Item was deleted",
+	"negative_stock":       "Stock can not become negative",
+	"bad_cursor":           "Wrong cursor",
+	"bad_price_range":      "price_min is greater than price_max",
+	"bad_decimal":          "Wrong decimal format",
+	"decimal_overflow":     "Amount is too large",
+	"unknown_measure_unit": "Provided measure unit does not exist",
+
+	// Корзина и заказы
+	"not_in_cart":           "Item is not in the cart",
+	"measure_unit_mismatch": "Measure unit does not match the item",
+	"fractional_quantity":   "Quantity must be whole for this measure unit",
+	"cart_empty":            "Cart is empty",
+	"out_of_stock":          "Not enough items in stock",
+	"bad_transition":        "Order status transition is not allowed",
+	"order_not_found":       "Order not found",
+
+	// Правила валидации
+	"rule.required":   "{0} is required",
+	"rule.email":      "{0} must be a valid email address",
+	"rule.oneof":      "{0} must be one of: {1}",
+	"rule.min":        "{0} must be at least {1}",
+	"rule.min.string": "{0} must be at least {1} characters long",
+	"rule.max":        "{0} must be at most {1}",
+	"rule.max.string": "{0} must be at most {1} characters long",
+	"rule.gt":         "{0} must be greater than {1}",
+	"rule.lt":         "{0} must be less than {1}",
+}
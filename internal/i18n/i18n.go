@@ -0,0 +1,111 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+	"golang.org/x/text/language"
+)
+
+// catalogs - тексты сообщений API по языкам, ключи - коды ошибок и правил валидации
+var catalogs = map[string]map[string]string{
+	"en": messagesEN,
+	"ru": messagesRU,
+}
+
+// Supported() - проверяет, что для языка есть каталог сообщений
+func Supported(lang string) bool {
+	_, ok := catalogs[lang]
+	return ok
+}
+
+// Languages() - список языков с каталогами сообщений
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+
+	return langs
+}
+
+// Catalog - переводчики сообщений API с выбором языка по заголовку Accept-Language
+type Catalog struct {
+	uni *ut.UniversalTranslator
+}
+
+/*
+New() - загружает каталоги всех языков в universal-translator.
+Язык fallback отдается клиентам, которые не прислали Accept-Language
+или просят только неподдерживаемые языки.
+*/
+func New(fallback string) (*Catalog, error) {
+	locs := map[string]locales.Translator{
+		"en": en.New(),
+		"ru": ru.New(),
+	}
+
+	if !Supported(fallback) {
+		return nil, fmt.Errorf("Language %q is not supported", fallback)
+	}
+
+	supported := make([]locales.Translator, 0, len(locs))
+	for _, lang := range Languages() {
+		supported = append(supported, locs[lang])
+	}
+
+	uni := ut.New(locs[fallback], supported...)
+	for lang, messages := range catalogs {
+		trans, _ := uni.GetTranslator(lang)
+		for key, text := range messages {
+			if err := trans.Add(key, text, false); err != nil {
+				return nil, fmt.Errorf("Catalog %s, key %s: %w", lang, key, err)
+			}
+		}
+	}
+
+	return &Catalog{uni: uni}, nil
+}
+
+/*
+Translator() - переводчик для значения заголовка Accept-Language.
+Берется первый поддерживаемый язык в порядке убывания q, регион не учитывается (ru-RU - это ru).
+*/
+func (c *Catalog) Translator(acceptLanguage string) ut.Translator {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+
+	bases := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		base, _ := tag.Base()
+		bases = append(bases, base.String())
+	}
+
+	trans, _ := c.uni.FindTranslator(bases...)
+	return trans
+}
+
+// Translators() - переводчики всех поддерживаемых языков, например, для регистрации правил валидатора
+func (c *Catalog) Translators() []ut.Translator {
+	var all []ut.Translator
+	for _, lang := range Languages() {
+		trans, _ := c.uni.GetTranslator(lang)
+		all = append(all, trans)
+	}
+
+	return all
+}
+
+// Text() - перевод ключа, а если его нет в каталоге - текст по умолчанию
+func Text(trans ut.Translator, key, fallback string, params ...string) string {
+	text, err := trans.T(key, params...)
+	if err != nil || text == "" {
+		return fallback
+	}
+
+	return text
+}
@@ -0,0 +1,78 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+func TestCatalogs(t *testing.T) {
+	for lang, messages := range catalogs {
+		for _, code := range models.Codes() {
+			assert.NotEmpty(t, messages[code], "%s: no message for %s", lang, code)
+		}
+
+		for key := range messagesEN {
+			assert.NotEmpty(t, messages[key], "%s: no message for %s", lang, key)
+		}
+		assert.Len(t, messages, len(messagesEN), "%s: keys differ from en", lang)
+	}
+}
+
+func TestTranslator(t *testing.T) {
+	catalog, err := New("en")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{ // no header
+			"",
+			"Cart is empty",
+		},
+		{ // exact language
+			"ru",
+			"Корзина пуста",
+		},
+		{ // region is ignored
+			"ru-RU",
+			"Корзина пуста",
+		},
+		{ // highest q wins
+			"en;q=0.5, ru;q=0.9",
+			"Корзина пуста",
+		},
+		{ // first supported language
+			"de, en;q=0.8, ru;q=0.5",
+			"Cart is empty",
+		},
+		{ // nothing supported
+			"de, fr",
+			"Cart is empty",
+		},
+		{ // broken header
+			";;;",
+			"Cart is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		trans := catalog.Translator(tt.acceptLanguage)
+		assert.Equal(t, tt.want, Text(trans, "cart_empty", "fallback"), tt.acceptLanguage)
+	}
+
+	assert.Equal(t, "fallback", Text(catalog.Translator(""), "no_such_key", "fallback"))
+
+	ru, err := New("ru")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Корзина пуста", Text(ru.Translator("de"), "cart_empty", ""))
+	}
+
+	_, err = New("de")
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package i18n
+
+// messagesRU - сообщения API на русском, ключи те же, что и в messagesEN
+var messagesRU = map[string]string{
+	// Ответы приложения
+	"validation_failed": "Данные не прошли проверку",
+	"bad_format":        "Неверный формат данных",
+	"internal":          "Внутренняя ошибка сервера",
+	"timeout":           "Превышено время обработки запроса",
+	"cancelled":         "Запрос отменен",
+
+	// Общие доменные ошибки
+	"not_found":         "Запись не найдена",
+	"already_exists":    "Запись уже существует",
+	"bad_reference":     "Связанная запись не существует",
+	"bad_identifier":    "Неверный формат идентификатора",
+	"nothing_to_update": "Нечего обновлять",
+	"access_denied":     "Доступ запрещен",
+	"version_mismatch":  "Запись изменилась после того, как ее прочитали",
+
+	// Пользователи, роли и токены
+	"user_not_found":        "Пользователь не найден",
+	"user_deleted":          "Пользователь удален",
+	"user_exists":           "Имя пользователя или email уже заняты",
+	"wrong_credentials":     "Неверный логин или пароль",
+	"unknown_country":       "Указанная страна не существует",
+	"unknown_role":          "Указанная роль не существует",
+	"own_admin_role":        "Администратор не может снять роль администратора с себя",
+	"token_reused":          "Обнаружено повторное использование refresh-токена",
+	"invalid_refresh_token": "Недействительный refresh-токен",
+	"refresh_token_expired": "Срок действия refresh-токена истек",
+	"invalid_token":         "Недействительный токен",
+	"token_revoked":         "Токен отозван",
+
+	// Магазины и товары
+	"shop_not_found":       "Магазин не найден",
+	"shop_deleted":         "Магазин удален",
+	"shop_exists":          "Название магазина уже занято",
+	"item_not_found":       "Товар не найден",
+	"item_deleted":         "Товар удален",
+	"negative_stock":       "Остаток не может стать отрицательным",
+	"bad_cursor":           "Неверный курсор",
+	"bad_price_range":      "price_min больше, чем price_max",
+	"bad_decimal":          "Неверный формат числа",
+	"decimal_overflow":     "Сумма слишком большая",
+	"unknown_measure_unit": "Указанная единица измерения не существует",
+
+	// Корзина и заказы
+	"not_in_cart":           "Товара нет в корзине",
+	"measure_unit_mismatch": "Единица измерения не совпадает с единицей товара",
+	"fractional_quantity":   "Для этой единицы измерения количество должно быть целым",
+	"cart_empty":            "Корзина пуста",
+	"out_of_stock":          "Недостаточно товара на складе",
+	"bad_transition":        "Такая смена статуса заказа не разрешена",
+	"order_not_found":       "Заказ не найден",
+
+	// Правила валидации
+	"rule.required":   "Поле {0} обязательно",
+	"rule.email":      "Поле {0} должно быть корректным email",
+	"rule.oneof":      "Поле {0} должно быть одним из: {1}",
+	"rule.min":        "Поле {0} должно быть не меньше {1}",
+	"rule.min.string": "Поле {0} должно содержать не меньше {1} символов",
+	"rule.max":        "Поле {0} должно быть не больше {1}",
+	"rule.max.string": "Поле {0} должно содержать не больше {1} символов",
+	"rule.gt":         "Поле {0} должно быть больше {1}",
+	"rule.lt":         "Поле {0} должно быть меньше {1}",
+}
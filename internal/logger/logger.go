@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level - уровень важности записи в логе
+type Level int
+
+// Уровни логирования по возрастанию важности
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+// String() - название уровня, как оно пишется в лог и в настройках
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel() - разбирает название уровня: debug, info, warn или error
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Unknown log level %q", s)
+}
+
+/*
+Logger - логгер, который пишет каждую запись одной JSON строкой:
+{"time":"...","level":"info","msg":"...", поля...}.
+Поля передаются парами ключ-значение, как в log/slog.
+With() создает дочерний логгер с постоянными полями (например, request_id),
+писать в один поток дочерние логгеры могут одновременно.
+*/
+type Logger struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []interface{}
+	now    func() time.Time
+}
+
+// New() - создает логгер, который пишет в out записи уровня level и выше
+func New(out io.Writer, level Level) *Logger {
+	return &Logger{
+		mu:    &sync.Mutex{},
+		out:   out,
+		level: level,
+		now:   time.Now,
+	}
+}
+
+// Discard() - логгер, который ничего не пишет, например, для тестов
+func Discard() *Logger {
+	return New(ioutil.Discard, LevelError+1)
+}
+
+// With() - дочерний логгер, который дописывает поля kv к каждой записи
+func (l *Logger) With(kv ...interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]interface{}(nil), l.fields...), kv...)
+
+	return &child
+}
+
+// Enabled() - проверяет, что записи уровня level попадут в лог
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug() - запись уровня debug
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.write(LevelDebug, msg, kv)
+}
+
+// Info() - запись уровня info
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.write(LevelInfo, msg, kv)
+}
+
+// Warn() - запись уровня warn
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.write(LevelWarn, msg, kv)
+}
+
+// Error() - запись уровня error
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.write(LevelError, msg, kv)
+}
+
+// write() - собирает запись в одну строку и пишет ее целиком под мьютексом
+func (l *Logger) write(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeValue(&buf, l.now().UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeValue(&buf, level.String())
+	buf.WriteString(`,"msg":`)
+	writeValue(&buf, msg)
+
+	writeFields(&buf, l.fields)
+	writeFields(&buf, kv)
+	buf.WriteString("}\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out.Write(buf.Bytes())
+}
+
+// writeFields() - дописывает пары ключ-значение, у последнего ключа без пары значение null
+func writeFields(buf *bytes.Buffer, kv []interface{}) {
+	for i := 0; i < len(kv); i += 2 {
+		buf.WriteByte(',')
+		writeValue(buf, fmt.Sprint(kv[i]))
+		buf.WriteByte(':')
+
+		if i+1 < len(kv) {
+			writeValue(buf, kv[i+1])
+		} else {
+			buf.WriteString("null")
+		}
+	}
+}
+
+// writeValue() - пишет значение в JSON; ошибки пишутся текстом, а то, что не сериализуется, - через fmt
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case error:
+		v = val.Error()
+	case fmt.Stringer:
+		v = val.String()
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprint(v))
+	}
+	buf.Write(b)
+}
+
+type ctxKey struct{}
+
+// NewContext() - контекст, который несет логгер, например, с request_id текущего запроса
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// Ctx() - логгер из контекста, а если его там нет - сам l
+func (l *Logger) Ctx(ctx context.Context) *Logger {
+	if cl, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return cl
+	}
+
+	return l
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := New(&buf, LevelInfo)
+	l.now = func() time.Time { return time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		write func()
+		want  string
+	}{
+		{ // below the level
+			func() { l.Debug("hidden") },
+			"",
+		},
+		{ // message only
+			func() { l.Info("Server started") },
+			`{"time":"2022-03-01T12:00:00Z","level":"info","msg":"Server started"}`,
+		},
+		{ // fields of different types
+			func() {
+				l.Warn("Request rejected", "status", 404, "err", errors.New("no \"shop\""), "latency", time.Second)
+			},
+			`{"time":"2022-03-01T12:00:00Z","level":"warn","msg":"Request rejected","status":404,"err":"no \"shop\"","latency":"1s"}`,
+		},
+		{ // fields of the child logger go first, key without a value
+			func() { l.With("request_id", "req-1").Error("Request failed", "code") },
+			`{"time":"2022-03-01T12:00:00Z","level":"error","msg":"Request failed","request_id":"req-1","code":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+		tt.write()
+		assert.Equal(t, tt.want, strings.TrimSuffix(buf.String(), "\n"))
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"debug", "info", "warn", "error"} {
+		level, err := ParseLevel(strings.ToUpper(name))
+		if assert.NoError(t, err) {
+			assert.Equal(t, name, level.String())
+		}
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestCtx(t *testing.T) {
+	root := Discard()
+	child := root.With("request_id", "req-1")
+
+	assert.Same(t, root, root.Ctx(context.Background()))
+	assert.Same(t, child, root.Ctx(NewContext(context.Background(), child)))
+}
@@ -0,0 +1,293 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets - границы корзин гистограммы времени ответа в секундах, как в клиенте prometheus
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// metric - то, что реестр умеет выводить: заголовки HELP/TYPE и значения рядов
+type metric interface {
+	write(buf *bytes.Buffer)
+}
+
+/*
+Registry - набор метрик, который отдается в текстовом формате prometheus (version 0.0.4).
+Метрики выводятся в порядке регистрации, ряды внутри метрики - по значениям меток,
+чтобы ответ не менялся от запуска к запуску.
+*/
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// NewRegistry() - создает пустой реестр
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register() - добавляет метрику, повтор имени - ошибка программиста
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s is already registered", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// ServeHTTP() - отдает все метрики реестра
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(r.Bytes())
+}
+
+// Bytes() - все метрики реестра в текстовом формате
+func (r *Registry) Bytes() []byte {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, m := range metrics {
+		m.write(&buf)
+	}
+
+	return buf.Bytes()
+}
+
+// vec - ряды одной метрики, различающиеся значениями меток
+type vec struct {
+	mu     sync.Mutex
+	name   string
+	help   string
+	typ    string
+	labels []string
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	sum    float64
+	count  uint64
+	counts []uint64
+}
+
+func newVec(name, help, typ string, labels []string) vec {
+	return vec{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*series)}
+}
+
+// get() - ряд для значений меток, создается при первом обращении; вызывается под v.mu
+func (v *vec) get(values []string, buckets int) *series {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...), counts: make([]uint64, buckets)}
+		v.series[key] = s
+	}
+
+	return s
+}
+
+// sorted() - ряды по возрастанию значений меток; вызывается под v.mu
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	all := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		all = append(all, v.series[key])
+	}
+
+	return all
+}
+
+func (v *vec) header(buf *bytes.Buffer) {
+	writeHeader(buf, v.name, v.help, v.typ)
+}
+
+// Counter - счетчик, который только растет
+type Counter struct {
+	vec
+}
+
+// NewCounter() - регистрирует счетчик с метками labels
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labels)}
+	r.register(name, c)
+
+	return c
+}
+
+// Inc() - увеличивает на единицу ряд со значениями меток values
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add() - увеличивает ряд на n, отрицательные n игнорируются
+func (c *Counter) Add(n float64, values ...string) {
+	if n < 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(values, 0).sum += n
+}
+
+// Value() - текущее значение ряда, например, для тестов
+func (c *Counter) Value(values ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if s, ok := c.series[strings.Join(values, "\xff")]; ok {
+		return s.sum
+	}
+
+	return 0
+}
+
+func (c *Counter) write(buf *bytes.Buffer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.header(buf)
+	if len(c.labels) == 0 && len(c.series) == 0 {
+		c.get(nil, 0)
+	}
+	for _, s := range c.sorted() {
+		writeSample(buf, c.name, c.labels, s.values, "", "", s.sum)
+	}
+}
+
+// Histogram - распределение наблюдений по корзинам с верхними границами buckets
+type Histogram struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogram() - регистрирует гистограмму, границы корзин должны возрастать
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+
+	h := &Histogram{newVec(name, help, "histogram", labels), append([]float64(nil), buckets...)}
+	r.register(name, h)
+
+	return h
+}
+
+// Observe() - добавляет наблюдение x в ряд со значениями меток values
+func (h *Histogram) Observe(x float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(values, len(h.buckets))
+	for i, le := range h.buckets {
+		if x <= le {
+			s.counts[i]++
+		}
+	}
+	s.sum += x
+	s.count++
+}
+
+func (h *Histogram) write(buf *bytes.Buffer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.header(buf)
+	for _, s := range h.sorted() {
+		for i, le := range h.buckets {
+			writeSample(buf, h.name+"_bucket", h.labels, s.values, "le", formatFloat(le), float64(s.counts[i]))
+		}
+		writeSample(buf, h.name+"_bucket", h.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(buf, h.name+"_sum", h.labels, s.values, "", "", s.sum)
+		writeSample(buf, h.name+"_count", h.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// funcMetric - метрика без меток, значение которой считается в момент выдачи
+type funcMetric struct {
+	name string
+	help string
+	typ  string
+	fn   func() float64
+}
+
+/*
+NewGaugeFunc() - регистрирует метрику, значение которой fn отдает при каждой выдаче.
+Подходит для состояния, которое и так где-то хранится, например, статистики пула соединений.
+*/
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name, help, "gauge", fn})
+}
+
+// NewCounterFunc() - как NewGaugeFunc(), но для значений, которые только растут
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name, help, "counter", fn})
+}
+
+func (f *funcMetric) write(buf *bytes.Buffer) {
+	writeHeader(buf, f.name, f.help, f.typ)
+	writeSample(buf, f.name, nil, nil, "", "", f.fn())
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeSample() - строка с одним значением; extra - дополнительная метка, например, le у корзин
+func writeSample(buf *bytes.Buffer, name string, labels, values []string, extra, extraValue string, v float64) {
+	buf.WriteString(name)
+
+	if len(labels) > 0 || extra != "" {
+		pairs := make([]string, 0, len(labels)+1)
+		for i, label := range labels {
+			pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+		}
+		if extra != "" {
+			pairs = append(pairs, extra+`="`+escapeLabel(extraValue)+`"`)
+		}
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	buf.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
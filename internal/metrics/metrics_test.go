@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	requests := r.NewCounter("http_requests_total", "Requests served.", "route", "status")
+	requests.Inc("/shop/:uid", "404")
+	requests.Inc("/item/:uid", "200")
+	requests.Add(2, "/item/:uid", "200")
+	requests.Add(-1, "/item/:uid", "200")
+
+	r.NewCounter("signups_total", "Users signed up.")
+
+	latency := r.NewHistogram("latency_seconds", "Time to \"answer\".", []float64{0.1, 1}, "route")
+	latency.Observe(0.05, `a"b\c`)
+	latency.Observe(0.5, `a"b\c`)
+	latency.Observe(3, `a"b\c`)
+
+	r.NewGaugeFunc("pool_open", "Open connections.", func() float64 { return 7 })
+
+	want := `# HELP http_requests_total Requests served.
+# TYPE http_requests_total counter
+http_requests_total{route="/item/:uid",status="200"} 3
+http_requests_total{route="/shop/:uid",status="404"} 1
+# HELP signups_total Users signed up.
+# TYPE signups_total counter
+signups_total 0
+# HELP latency_seconds Time to "answer".
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="a\"b\\c",le="0.1"} 1
+latency_seconds_bucket{route="a\"b\\c",le="1"} 2
+latency_seconds_bucket{route="a\"b\\c",le="+Inf"} 3
+latency_seconds_sum{route="a\"b\\c"} 3.55
+latency_seconds_count{route="a\"b\\c"} 3
+# HELP pool_open Open connections.
+# TYPE pool_open gauge
+pool_open 7
+`
+	assert.Equal(t, want, string(r.Bytes()))
+	assert.Equal(t, float64(3), requests.Value("/item/:uid", "200"))
+	assert.Equal(t, float64(0), requests.Value("/cart", "200"))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, want, rec.Body.String())
+}
+
+func TestRegistryMisuse(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests.", "route")
+
+	assert.Panics(t, func() { r.NewCounter("requests_total", "Again.") })
+	assert.Panics(t, func() { c.Inc("/a", "200") })
+	assert.Panics(t, func() { r.NewHistogram("latency", "Latency.", []float64{1, 0.5}) })
+}
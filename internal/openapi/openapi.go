@@ -0,0 +1,314 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version - версия спецификации OpenAPI, в которой описывается документ
+const Version = "3.0.3"
+
+// Document - документ OpenAPI 3, описаны только те части спецификации, которые нужны апи
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	types map[reflect.Type]*Schema
+}
+
+// Info - название и версия апи
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem - операции одного пути по методам: get, post, put, delete...
+type PathItem map[string]*Operation
+
+// Operation - одна операция апи
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+// Parameter - параметр пути, строки запроса или заголовок
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody - тело запроса
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response - ответ с одним кодом статуса
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Header - заголовок ответа
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType - схема тела для одного типа содержимого
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components - переиспользуемые схемы и способы авторизации
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme - способ авторизации
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Schema - JSON схема значения
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Example     interface{}        `json:"example,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// New() - пустой документ
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+		types: make(map[reflect.Type]*Schema),
+	}
+}
+
+/*
+DefineType() - задает схему для типа, который сам решает, как выглядит в JSON
+(например, models.Decimal пишется строкой). Схема подставляется везде как есть, без $ref.
+*/
+func (d *Document) DefineType(v interface{}, s *Schema) {
+	d.types[reflect.TypeOf(v)] = s
+}
+
+// Add() - добавляет операцию; путь в формате OpenAPI, с параметрами в фигурных скобках
+func (d *Document) Add(method, path string, op *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+
+	(*item)[strings.ToLower(method)] = op
+}
+
+/*
+Schema() - схема значения v. Структуры попадают в components/schemas под именем типа
+и отдаются ссылкой $ref. Поля называются по тэгу json, ограничения берутся из тэга validate:
+required, min, max, oneof, email.
+*/
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+/*
+QueryParameters() - параметры строки запроса из полей структуры v с тэгом query,
+с теми же ограничениями из тэга validate, что и у полей тела
+*/
+func (d *Document) QueryParameters(v interface{}) []*Parameter {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get("query"), ",", 2)[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		s, required := d.fieldSchema(f)
+		params = append(params, &Parameter{Name: name, In: "query", Required: required, Schema: s})
+	}
+
+	return params
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if s, ok := d.types[t]; ok {
+		return copySchema(s)
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return d.schemaOf(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		return d.structRef(t)
+	}
+
+	return &Schema{}
+}
+
+// structRef() - регистрирует схему структуры в components/schemas и отдает ссылку на нее
+func (d *Document) structRef(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if _, ok := d.Components.Schemas[t.Name()]; ok {
+		return ref
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	// Ставим заглушку до обхода полей, чтобы рекурсивные типы не зациклились
+	d.Components.Schemas[t.Name()] = s
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := f.Name
+		if tag := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]; tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		}
+
+		fs, required := d.fieldSchema(f)
+		s.Properties[name] = fs
+		if required {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return ref
+}
+
+// fieldSchema() - схема поля с ограничениями из тэга validate и признак обязательности
+func (d *Document) fieldSchema(f reflect.StructField) (*Schema, bool) {
+	s := d.schemaOf(f.Type)
+	required := false
+
+	for _, rule := range strings.Split(f.Tag.Get("validate"), ",") {
+		name, param := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			// У полей частичного обновления (тип задан через DefineType с Nullable) поле можно
+			// не передавать, а required запрещает только null
+			if s.Nullable {
+				s.Nullable = false
+			} else {
+				required = true
+			}
+		case "email":
+			s.Format = "email"
+		case "oneof":
+			s.Enum = strings.Fields(param)
+		case "min", "gte":
+			limit(s, param, true)
+		case "max", "lte":
+			limit(s, param, false)
+		}
+	}
+
+	return s, required
+}
+
+// limit() - нижняя или верхняя граница: длина для строк, значение для чисел
+func limit(s *Schema, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch s.Type {
+	case "string":
+		if s.Format != "" || s.Pattern != "" {
+			// Строки со своим форматом (например, десятичные числа) ограничиваются по значению,
+			// а не по длине, поэтому границу здесь не описать
+			return
+		}
+		l := int(n)
+		if lower {
+			s.MinLength = &l
+		} else {
+			s.MaxLength = &l
+		}
+	case "integer", "number":
+		if lower {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+func copySchema(s *Schema) *Schema {
+	c := *s
+	return &c
+}
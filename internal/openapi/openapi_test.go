@@ -0,0 +1,100 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type money int64
+
+type node struct {
+	Name     string    `json:"Name" validate:"required,max=60"`
+	Email    string    `json:"Email" validate:"omitempty,email"`
+	Kind     string    `json:"Kind" validate:"oneof=a b"`
+	Count    int       `json:"Count" validate:"min=0,max=10"`
+	Price    money     `json:"Price" validate:"gt=0,max=100"`
+	Secret   string    `json:"-"`
+	Created  time.Time `json:",omitempty"`
+	Children []*node
+	hidden   string
+}
+
+type search struct {
+	Query string `query:"q" validate:"required,max=255"`
+	Limit int    `query:"limit" validate:"min=0,max=100"`
+	Other string
+}
+
+func TestSchema(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.DefineType(money(0), &Schema{Type: "string", Pattern: `^\d+$`})
+
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/node"}, doc.Schema(&node{}))
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, doc.Schema([]string{}))
+
+	b, err := json.Marshal(doc.Components.Schemas["node"])
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{
+			"type": "object",
+			"properties": {
+				"Name": {"type": "string", "maxLength": 60},
+				"Email": {"type": "string", "format": "email"},
+				"Kind": {"type": "string", "enum": ["a", "b"]},
+				"Count": {"type": "integer", "format": "int32", "minimum": 0, "maximum": 10},
+				"Price": {"type": "string", "pattern": "^\\d+$"},
+				"Created": {"type": "string", "format": "date-time"},
+				"Children": {"type": "array", "items": {"$ref": "#/components/schemas/node"}}
+			},
+			"required": ["Name"]
+		}`, string(b))
+	}
+
+	params := doc.QueryParameters(search{})
+	if assert.Len(t, params, 2) {
+		assert.Equal(t, "q", params[0].Name)
+		assert.True(t, params[0].Required)
+		assert.Equal(t, 255, *params[0].Schema.MaxLength)
+		assert.Equal(t, "limit", params[1].Name)
+		assert.Equal(t, float64(100), *params[1].Schema.Maximum)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("GET", "/shop/{uid}", &Operation{OperationID: "getShop"})
+	doc.Add("DELETE", "/shop/{uid}", &Operation{OperationID: "deleteShop"})
+
+	assert.Len(t, doc.Paths, 1)
+	assert.Equal(t, "getShop", (*doc.Paths["/shop/{uid}"])["get"].OperationID)
+	assert.Equal(t, "deleteShop", (*doc.Paths["/shop/{uid}"])["delete"].OperationID)
+}
+
+type optional struct {
+	Value string
+	Set   bool
+}
+
+type patch struct {
+	Email optional `json:"Email" validate:"required,email"`
+	Phone optional `json:"Phone" validate:"max=30"`
+}
+
+func TestNullableSchema(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.DefineType(optional{}, &Schema{Type: "string", Nullable: true})
+	doc.Schema(patch{})
+
+	b, err := json.Marshal(doc.Components.Schemas["patch"])
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{
+			"type": "object",
+			"properties": {
+				"Email": {"type": "string", "format": "email"},
+				"Phone": {"type": "string", "nullable": true, "maxLength": 30}
+			}
+		}`, string(b))
+	}
+}
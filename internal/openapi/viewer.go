@@ -0,0 +1,11 @@
+package openapi
+
+import _ "embed"
+
+/*
+Viewer - страница для чтения документа в браузере, вшитая в бинарник.
+Страница самодостаточная, без внешних скриптов: забирает /openapi.json
+рядом с собой и рисует операции, параметры и схемы.
+*/
+//go:embed viewer.html
+var Viewer []byte
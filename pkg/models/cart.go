@@ -0,0 +1,69 @@
+package models
+
+var (
+	// ErrNotInCart - товара нет в корзине
+	ErrNotInCart = newError(KindNotFound, "not_in_cart", "Item is not in the cart")
+	// ErrUnitMismatch - единица измерения строки корзины не совпадает с единицей товара
+	ErrUnitMismatch = newError(KindInvalid, "measure_unit_mismatch", "Measure unit does not match the item")
+	// ErrFractionalQuantity - дробное количество для штучной единицы измерения
+	ErrFractionalQuantity = newError(KindInvalid, "fractional_quantity", "Quantity must be whole for this measure unit")
+)
+
+// CartItemInput - структура запроса в апи для добавления товара в корзину или изменения его количества.
+// Единицу измерения задает товар, если она указана, то должна с ней совпадать
+type CartItemInput struct {
+	ItemUID     string  `json:"ItemUID" validate:"required"`
+	Quantity    Decimal `json:"Quantity" validate:"gt=0,lt=10000000000"`
+	MeasureUnit string  `json:"MeasureUnit" validate:"max=30"`
+}
+
+// CartLineOutput - строка корзины с текущими ценой и остатком товара
+type CartLineOutput struct {
+	ItemUID     string
+	ShopUID     string
+	Name        string
+	Vendor      string
+	Price       Decimal
+	Quantity    Decimal
+	MeasureUnit string
+	LineTotal   Decimal
+	InStock     Decimal
+	Deleted     bool
+	Available   bool
+}
+
+// CartOutput - вью апи для корзины пользователя
+type CartOutput struct {
+	Lines []*CartLineOutput
+	Total Decimal
+}
+
+// DefaultMeasureUnit - единица измерения по умолчанию
+const DefaultMeasureUnit = "pcs"
+
+// MaxQuantity - наибольшее количество в строке корзины, как в CartItemInput и numeric(10,2) в cart_items
+const MaxQuantity Decimal = 9999999999
+
+/*
+Compute() - пересчитывает суммы строк, их доступность и итог корзины.
+Отдает ErrDecimalOverflow, если сумма не помещается в Decimal.
+*/
+func (co *CartOutput) Compute() error {
+	var err error
+	co.Total = 0
+
+	for _, l := range co.Lines {
+		l.LineTotal, err = l.Price.Mul(l.Quantity)
+		if err != nil {
+			return err
+		}
+
+		l.Available = !l.Deleted && l.Quantity <= l.InStock
+		co.Total, err = co.Total.Add(l.LineTotal)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
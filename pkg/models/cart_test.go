@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	co := CartOutput{Lines: []*CartLineOutput{
+		// piece goods
+		{Price: 1999, Quantity: 200, InStock: 200},
+		// fractional quantity is charged as is
+		{Price: 50000, Quantity: 150, InStock: 150},
+		// quantity exceeds stock
+		{Price: 100, Quantity: 50, InStock: 49},
+	}}
+
+	if assert.NoError(t, co.Compute()) {
+		assert.Equal(t, "39.98", co.Lines[0].LineTotal.String())
+		assert.Equal(t, "750.00", co.Lines[1].LineTotal.String())
+		assert.Equal(t, "0.50", co.Lines[2].LineTotal.String())
+		assert.True(t, co.Lines[0].Available)
+		assert.True(t, co.Lines[1].Available)
+		assert.False(t, co.Lines[2].Available)
+		assert.Equal(t, "790.48", co.Total.String())
+	}
+}
@@ -1,5 +1,8 @@
 package models
 
+// ErrUnknownCountry - страны нет в справочнике
+var ErrUnknownCountry = newError(KindInvalid, "unknown_country", "Provided country does not exist")
+
 // CountryOutput - структура на выход, в которую апи кладет список стран
 type CountryOutput struct {
 	UUID string `json:"-"`
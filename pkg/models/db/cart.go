@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// CartModel - модель сущности cart_items, корзина хранится на сервере отдельно для каждого пользователя
+type CartModel struct {
+	DB *sql.DB
+}
+
+// Get() - метод для получения корзины пользователя с текущими ценами и остатками
+func (c *CartModel) Get(ctx context.Context, uid string) (*models.CartOutput, error) {
+	var co models.CartOutput
+
+	rows, err := c.DB.QueryContext(ctx, stmts.GET_CART, uid)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			l      models.CartLineOutput
+			active bool
+		)
+
+		err = rows.Scan(
+			&l.ItemUID,
+			&l.ShopUID,
+			&l.Name,
+			&l.Vendor,
+			&l.Price,
+			&l.Quantity,
+			&l.MeasureUnit,
+			&l.InStock,
+			&active,
+		)
+		if err != nil {
+			return nil, dbError(err)
+		}
+
+		l.Deleted = !active
+		co.Lines = append(co.Lines, &l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	if err := co.Compute(); err != nil {
+		return nil, err
+	}
+
+	return &co, nil
+}
+
+// Put() - метод для добавления товара в корзину или замены его количества
+func (c *CartModel) Put(ctx context.Context, uid, iuid string, quantity models.Decimal, muUID string) error {
+	_, err := c.DB.ExecContext(ctx, stmts.PUT_CART_ITEM, uid, iuid, quantity, muUID)
+	return dbError(err)
+}
+
+// Add() - метод для добавления количества товара в корзину одним запросом, без гонки чтения и записи
+func (c *CartModel) Add(ctx context.Context, uid, iuid string, quantity models.Decimal, muUID string) error {
+	_, err := c.DB.ExecContext(ctx, stmts.ADD_CART_ITEM, uid, iuid, quantity, muUID)
+	if err != nil {
+		// Сумма не поместилась в numeric(10,2)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "22003" {
+			return models.ErrDecimalOverflow
+		}
+		return dbError(err)
+	}
+
+	return nil
+}
+
+// Remove() - метод для удаления товара из корзины
+func (c *CartModel) Remove(ctx context.Context, uid, iuid string) error {
+	res, err := c.DB.ExecContext(ctx, stmts.REMOVE_CART_ITEM, uid, iuid)
+	if err != nil {
+		return dbError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+	if affected == 0 {
+		return models.ErrNotInCart
+	}
+
+	return nil
+}
+
+// Clear() - метод для очистки корзины пользователя
+func (c *CartModel) Clear(ctx context.Context, uid string) error {
+	_, err := c.DB.ExecContext(ctx, stmts.CLEAR_CART, uid)
+	return dbError(err)
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+func TestCartAdd(t *testing.T) {
+	const adds = 10
+
+	ctx := context.Background()
+	conn := openTestDB(t)
+	u, _ := uuid.NewV4()
+
+	seller := insertTestUser(t, conn, "seller")
+	buyer := insertTestUser(t, conn, "buyer")
+
+	suid, err := (&ShopModel{DB: conn}).Insert(ctx, seller, &models.ShopInput{Name: "shop-" + u.String()[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iuid, err := (&ItemModel{DB: conn}).Insert(ctx, &models.ItemInput{ShopUID: suid, Name: "Added", Vendor: "TestVendor", Price: 100, InStock: 10000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcs, err := (&UnitModel{DB: conn}).GetByName(ctx, models.DefaultMeasureUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carts := &CartModel{DB: conn}
+
+	// Параллельные добавления не теряют друг друга
+	var wg sync.WaitGroup
+	for i := 0; i < adds; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, carts.Add(ctx, buyer, iuid, 100, pcs.UUID))
+		}()
+	}
+	wg.Wait()
+
+	co, err := carts.Get(ctx, buyer)
+	if assert.NoError(t, err) && assert.Len(t, co.Lines, 1) {
+		assert.Equal(t, "10.00", co.Lines[0].Quantity.String())
+	}
+
+	// Сумма, не помещающаяся в numeric(10,2)
+	err = carts.Add(ctx, buyer, iuid, models.MaxQuantity, pcs.UUID)
+	assert.True(t, errors.Is(err, models.ErrDecimalOverflow))
+}
@@ -1,8 +1,8 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 
 	"github.com/JohanVong/online_bazaar/internal/db/stmts"
 	"github.com/JohanVong/online_bazaar/pkg/models"
@@ -14,15 +14,12 @@ type CountryModel struct {
 }
 
 // GetList() - метод, который достает список всех стран из БД
-func (c *CountryModel) GetList() ([]*models.CountryOutput, error) {
+func (c *CountryModel) GetList(ctx context.Context) ([]*models.CountryOutput, error) {
 	var countries []*models.CountryOutput
 
-	rows, err := c.DB.Query(stmts.GET_COUNTRIES)
+	rows, err := c.DB.QueryContext(ctx, stmts.GET_COUNTRIES)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("No records found")
-		}
-		return nil, err
+		return nil, dbError(err)
 	}
 	defer rows.Close()
 
@@ -30,28 +27,25 @@ func (c *CountryModel) GetList() ([]*models.CountryOutput, error) {
 		c := &models.CountryOutput{}
 		err = rows.Scan(&c.UUID, &c.Name)
 		if err != nil {
-			return nil, err
+			return nil, dbError(err)
 		}
 		countries = append(countries, c)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, err
+		return nil, dbError(err)
 	}
 
 	return countries, nil
 }
 
 // GetByName() - метод, который достает ключ по названию страны
-func (c *CountryModel) GetByName(name string) (string, error) {
+func (c *CountryModel) GetByName(ctx context.Context, name string) (string, error) {
 	var cuid string
 
-	row := c.DB.QueryRow(stmts.GET_COUNTRY_PK, name)
+	row := c.DB.QueryRowContext(ctx, stmts.GET_COUNTRY_PK, name)
 	err := row.Scan(&cuid)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", errors.New("Provided country does not exist")
-		}
-		return "", err
+		return "", dbError(err, models.ErrUnknownCountry)
 	}
 
 	return cuid, nil
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// Коды ошибок постгрес, у которых есть доменный смысл
+const (
+	pqUniqueViolation     = "23505"
+	pqForeignKeyViolation = "23503"
+	pqInvalidText         = "22P02"
+)
+
+/*
+dbError() - переводит ошибки БД в доменные ошибки models, исходная ошибка остается внутри для логов.
+Уточненные ошибки сущности (например, models.ErrShopNotFound) подменяют общие того же вида.
+Остальные ошибки, в том числе уже доменные, отдаются как есть.
+*/
+func dbError(err error, specific ...*models.Error) error {
+	var (
+		pqErr  *pq.Error
+		domain *models.Error
+	)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, sql.ErrNoRows):
+		domain = models.ErrNotFound
+	case errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation:
+		domain = models.ErrAlreadyExists
+	case errors.As(err, &pqErr) && pqErr.Code == pqForeignKeyViolation:
+		domain = models.ErrBadReference
+	case errors.As(err, &pqErr) && pqErr.Code == pqInvalidText:
+		domain = models.ErrBadIdentifier
+	default:
+		return err
+	}
+
+	for _, s := range specific {
+		if s.Kind == domain.Kind {
+			domain = s
+			break
+		}
+	}
+
+	return domain.Wrap(err)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+func TestDBError(t *testing.T) {
+	tests := []struct {
+		err      error
+		specific []*models.Error
+		want     error
+	}{
+		{ // no error
+			nil,
+			nil,
+			nil,
+		},
+		{ // no rows without a specific error
+			sql.ErrNoRows,
+			nil,
+			models.ErrNotFound,
+		},
+		{ // no rows with a specific error of the same kind
+			sql.ErrNoRows,
+			[]*models.Error{models.ErrShopExists, models.ErrShopNotFound},
+			models.ErrShopNotFound,
+		},
+		{ // unique violation
+			&pq.Error{Code: "23505"},
+			[]*models.Error{models.ErrUserExists},
+			models.ErrUserExists,
+		},
+		{ // foreign key violation, specific error of another kind is ignored
+			&pq.Error{Code: "23503"},
+			[]*models.Error{models.ErrUserNotFound},
+			models.ErrBadReference,
+		},
+		{ // malformed uuid
+			&pq.Error{Code: "22P02"},
+			nil,
+			models.ErrBadIdentifier,
+		},
+		{ // timeouts stay as they are
+			context.DeadlineExceeded,
+			[]*models.Error{models.ErrShopNotFound},
+			context.DeadlineExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		got := dbError(tt.err, tt.specific...)
+		if tt.want == nil {
+			assert.NoError(t, got)
+			continue
+		}
+
+		assert.True(t, errors.Is(got, tt.want), "%v", got)
+		assert.True(t, errors.Is(got, tt.err), "cause of %v is lost", got)
+	}
+}
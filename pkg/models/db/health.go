@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+)
+
+// HealthModel - проверки БД для готовности сервиса принимать запросы
+type HealthModel struct {
+	DB *sql.DB
+}
+
+// Ping() - метод, который проверяет, что БД отвечает
+func (h *HealthModel) Ping(ctx context.Context) error {
+	return h.DB.PingContext(ctx)
+}
+
+// SchemaVersion() - метод, который достает версию последней примененной миграции
+func (h *HealthModel) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+
+	row := h.DB.QueryRowContext(ctx, stmts.GET_SCHEMA_VERSION)
+	err := row.Scan(&version)
+	if err != nil {
+		return 0, dbError(err)
+	}
+
+	return version, nil
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/internal/db/migrate"
+)
+
+func TestHealthModel(t *testing.T) {
+	conn := openTestDB(t)
+	h := &HealthModel{DB: conn}
+	ctx := context.Background()
+
+	assert.NoError(t, h.Ping(ctx))
+
+	latest, err := migrate.New(conn).Latest()
+	if assert.NoError(t, err) {
+		version, err := h.SchemaVersion(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, latest, version)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, h.Ping(cancelled))
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+/*
+touchHistory() - отмечает изменение записи в ее истории. Если в контексте есть ожидаемые
+версии записи (models.WithExpectedVersions), история меняется, только когда версия совпала,
+иначе отдается ErrVersionMismatch и транзакцию нужно откатить. Строка histories при этом
+блокируется до конца транзакции, поэтому два конкурентных изменения с одной версией не пройдут оба.
+*/
+func touchHistory(ctx context.Context, tx *sql.Tx, huid string, now time.Time) error {
+	return markHistory(ctx, tx, huid, now, stmts.UPDATE_HISTORY, stmts.UPDATE_HISTORY_IF_VERSION)
+}
+
+// deleteHistory() - то же, что touchHistory(), только отмечает фейковое удаление записи
+func deleteHistory(ctx context.Context, tx *sql.Tx, huid string, now time.Time) error {
+	return markHistory(ctx, tx, huid, now, stmts.DELETE_HISTORY, stmts.DELETE_HISTORY_IF_VERSION)
+}
+
+// markHistory() - общая часть touchHistory() и deleteHistory(): безусловная квери или квери с версиями
+func markHistory(ctx context.Context, tx *sql.Tx, huid string, now time.Time, query, queryIfVersion string) error {
+	versions, ok := models.ExpectedVersions(ctx)
+	if !ok {
+		_, err := tx.ExecContext(ctx, query, now, huid)
+		return dbError(err)
+	}
+
+	stamps := make([]string, 0, len(versions))
+	for _, v := range versions {
+		stamps = append(stamps, v.Format("2006-01-02 15:04:05.999999"))
+	}
+
+	res, err := tx.ExecContext(ctx, queryIfVersion, now, huid, pq.Array(stamps))
+	if err != nil {
+		return dbError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+
+	if n == 0 {
+		return models.ErrVersionMismatch
+	}
+
+	return nil
+}
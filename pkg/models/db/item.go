@@ -0,0 +1,438 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// ItemModel - модель сущности items
+type ItemModel struct {
+	DB *sql.DB
+}
+
+// Insert() - метод для создания нового товара в магазине, отдает ключ товара
+func (i *ItemModel) Insert(ctx context.Context, input *models.ItemInput) (string, error) {
+	huid, _ := uuid.NewV6()
+	iuid, _ := uuid.NewV6()
+	now := time.Now()
+
+	unit := input.MeasureUnit
+	if unit == "" {
+		unit = models.DefaultMeasureUnit
+	}
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_HISTORY, huid.String(), now, nil, nil)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_ITEM, iuid.String(), input.Name, input.Vendor, input.Price, input.Description, input.InStock, input.ShopUID, huid.String(), now, unit)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	return iuid.String(), nil
+}
+
+// Get() - метод для получения данных о товаре по ключу
+func (i *ItemModel) Get(ctx context.Context, iuid string) (*models.ItemOutput, error) {
+	row := i.DB.QueryRowContext(ctx, stmts.GET_ITEM_BY_PK, iuid)
+
+	io, err := scanItem(row)
+	if err != nil {
+		return nil, dbError(err, models.ErrItemNotFound)
+	}
+
+	return io, nil
+}
+
+// GetList() - метод для получения списка действующих товаров магазина
+func (i *ItemModel) GetList(ctx context.Context, suid string) ([]*models.ItemOutput, error) {
+	var items []*models.ItemOutput
+
+	rows, err := i.DB.QueryContext(ctx, stmts.GET_ITEMS_BY_SHOP, suid)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		io, err := scanItem(rows)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		items = append(items, io)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	return items, nil
+}
+
+// searchSort - описание сортировки поиска товаров для построения условия курсора
+type searchSort struct {
+	column string
+	cast   string
+	desc   bool
+}
+
+// searchSorts - доступные сортировки поиска, ключ - параметр sort запроса
+var searchSorts = map[string]searchSort{
+	"price_asc":  {"price", "numeric", false},
+	"price_desc": {"price", "numeric", true},
+	"newest":     {"created_at", "timestamp", true},
+	"rating":     {"rating", "numeric", true},
+}
+
+// searchCursor - содержимое курсора: сортировка, значение ее колонки и ключ последнего товара страницы
+type searchCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	UID   string `json:"u"`
+}
+
+/*
+Search() - метод для поиска товаров с фильтрами и keyset-пагинацией.
+Вместо OFFSET следующая страница ищется по паре (значение сортировки, ключ товара)
+последней записи предыдущей страницы, поэтому глубокие страницы не тормозят.
+*/
+func (i *ItemModel) Search(ctx context.Context, input *models.ItemSearchInput) (*models.ItemSearchOutput, error) {
+	var (
+		filters strings.Builder
+		args    []interface{}
+		out     models.ItemSearchOutput
+	)
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if input.Query != "" {
+		filters.WriteString(" AND i.search @@ plainto_tsquery('simple', " + arg(input.Query) + ")")
+	}
+	if input.PriceMin > 0 {
+		filters.WriteString(" AND i.price >= " + arg(input.PriceMin))
+	}
+	if input.PriceMax > 0 {
+		filters.WriteString(" AND i.price <= " + arg(input.PriceMax))
+	}
+	if input.ShopUID != "" {
+		filters.WriteString(" AND i.shop_uid = " + arg(input.ShopUID))
+	}
+	if input.Vendor != "" {
+		filters.WriteString(" AND lower(i.vendor) = lower(" + arg(input.Vendor) + ")")
+	}
+	if input.InStock {
+		filters.WriteString(" AND i.in_stock > 0")
+	}
+
+	sortName := input.Sort
+	if sortName == "" {
+		sortName = "newest"
+	}
+	sort, ok := searchSorts[sortName]
+	if !ok {
+		return nil, models.ErrBadCursor
+	}
+
+	order, cmp := "ASC", ">"
+	if sort.desc {
+		order, cmp = "DESC", "<"
+	}
+
+	query := fmt.Sprintf(stmts.SEARCH_ITEMS, filters.String())
+
+	if input.Cursor != "" {
+		cur, err := decodeCursor(input.Cursor)
+		if err != nil || cur.Sort != sortName {
+			return nil, models.ErrBadCursor
+		}
+		query += fmt.Sprintf(" WHERE (%s, item_uid) %s (%s::%s, %s::uuid)",
+			sort.column, cmp, arg(cur.Value), sort.cast, arg(cur.UID))
+	}
+
+	limit := input.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	query += fmt.Sprintf(" ORDER BY %s %s, item_uid %s LIMIT %s;", sort.column, order, order, arg(limit+1))
+
+	rows, err := i.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var io models.ItemOutput
+		err = rows.Scan(
+			&io.ItemUID,
+			&io.ShopUID,
+			&io.OwnerUID,
+			&io.Name,
+			&io.Vendor,
+			&io.Price,
+			&io.Description,
+			&io.InStock,
+			&io.MeasureUnit,
+			&io.HistoryUID,
+			&io.CreatedAt,
+			&io.UpdatedAt,
+			&io.DeletedAt,
+			&io.Rating,
+		)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		out.Items = append(out.Items, &io)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	if len(out.Items) > limit {
+		out.Items = out.Items[:limit]
+		last := out.Items[limit-1]
+
+		cur := searchCursor{Sort: sortName, UID: last.ItemUID}
+		switch sort.column {
+		case "price":
+			cur.Value = last.Price.String()
+		case "rating":
+			cur.Value = last.Rating.String()
+		case "created_at":
+			cur.Value = last.CreatedAt.Format("2006-01-02 15:04:05.999999")
+		}
+		out.NextCursor = encodeCursor(cur)
+	}
+
+	return &out, nil
+}
+
+// Update() - метод для обновления некоторых данных товара
+func (i *ItemModel) Update(ctx context.Context, iuid string, input *models.ItemUpdateInput) error {
+	var (
+		huid    string
+		counter int
+	)
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if input.Name != "" {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE items SET name = $1 WHERE item_uid = $2", input.Name, iuid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	if input.Vendor != "" {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE items SET vendor = $1 WHERE item_uid = $2", input.Vendor, iuid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	if input.Price != 0 {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE items SET price = $1 WHERE item_uid = $2", input.Price, iuid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	if input.Description != "" {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE items SET description = $1 WHERE item_uid = $2", input.Description, iuid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	if counter <= 0 {
+		tx.Rollback()
+		return models.ErrNothingToUpdate
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM items WHERE item_uid = $1", iuid)
+	err = row.Scan(&huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrItemNotFound)
+	}
+
+	err = touchHistory(ctx, tx, huid, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
+}
+
+// Restock() - метод для изменения остатка товара на delta единиц
+func (i *ItemModel) Restock(ctx context.Context, iuid string, delta models.Decimal) error {
+	var huid string
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.RESTOCK_ITEM, delta, iuid)
+	if err != nil {
+		tx.Rollback()
+		// Сработал CHECK (in_stock >= 0) или остаток не поместился в numeric(12,2)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+			return models.ErrNegativeStock
+		}
+		if errors.As(err, &pqErr) && pqErr.Code == "22003" {
+			return models.ErrDecimalOverflow
+		}
+		return dbError(err)
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM items WHERE item_uid = $1", iuid)
+	err = row.Scan(&huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrItemNotFound)
+	}
+
+	err = touchHistory(ctx, tx, huid, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
+}
+
+// Delete() - метод для фейкового удаления товара через поле deleted_at в его истории
+func (i *ItemModel) Delete(ctx context.Context, iuid string) error {
+	var huid string
+
+	tx, err := i.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM items WHERE item_uid = $1", iuid)
+	err = row.Scan(&huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrItemNotFound)
+	}
+
+	err = deleteHistory(ctx, tx, huid, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return dbError(tx.Commit())
+}
+
+// encodeCursor() - упаковывает курсор поиска в непрозрачную для клиента строку
+func encodeCursor(cur searchCursor) string {
+	b, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor() - распаковывает курсор поиска
+func decodeCursor(s string) (*searchCursor, error) {
+	var cur searchCursor
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	err = json.Unmarshal(b, &cur)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	// Курсор приходит от клиента, поэтому его значения проверяются до подстановки в запрос
+	if _, err = uuid.FromString(cur.UID); err != nil {
+		return nil, dbError(err)
+	}
+
+	switch searchSorts[cur.Sort].cast {
+	case "numeric":
+		_, err = models.ParseDecimal(cur.Value)
+	case "timestamp":
+		_, err = time.Parse("2006-01-02 15:04:05.999999", cur.Value)
+	default:
+		err = models.ErrBadCursor
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &cur, nil
+}
+
+// scanItem() - сканирует строку выборки get_item в структуру товара
+func scanItem(row interface{ Scan(...interface{}) error }) (*models.ItemOutput, error) {
+	var io models.ItemOutput
+
+	err := row.Scan(
+		&io.ItemUID,
+		&io.ShopUID,
+		&io.OwnerUID,
+		&io.Name,
+		&io.Vendor,
+		&io.Price,
+		&io.Description,
+		&io.InStock,
+		&io.MeasureUnit,
+		&io.HistoryUID,
+		&io.CreatedAt,
+		&io.UpdatedAt,
+		&io.DeletedAt,
+	)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &io, nil
+}
@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// OrderModel - модель сущностей orders и order_to_item
+type OrderModel struct {
+	DB *sql.DB
+}
+
+// cartLine - строка корзины, которую переносим в заказ
+type cartLine struct {
+	itemUID  string
+	quantity models.Decimal
+	muUID    string
+	price    models.Decimal
+}
+
+/*
+Checkout() - метод для оформления заказа из корзины пользователя, отдает ключ заказа.
+Все делается в одной транзакции: строки корзины и товаров блокируются (FOR UPDATE)
+всегда в порядке ключей товаров, чтобы параллельные оформления не ловили дедлоки,
+а ждали друг друга. Поэтому последнюю единицу товара не смогут купить дважды:
+второе оформление увидит уже уменьшенный остаток и получит models.ErrOutOfStock.
+Товары удаленных магазинов считаются недоступными, единица измерения строки берется у товара.
+*/
+func (o *OrderModel) Checkout(ctx context.Context, uid string) (string, error) {
+	var (
+		lines []*cartLine
+		total models.Decimal
+	)
+
+	huid, _ := uuid.NewV6()
+	ouid, _ := uuid.NewV6()
+	now := time.Now()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	rows, err := tx.QueryContext(ctx, stmts.LOCK_CART, uid)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	for rows.Next() {
+		l := &cartLine{}
+		err = rows.Scan(&l.itemUID, &l.quantity, &l.muUID)
+		if err != nil {
+			rows.Close()
+			tx.Rollback()
+			return "", dbError(err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	if len(lines) == 0 {
+		tx.Rollback()
+		return "", models.ErrEmptyCart
+	}
+
+	for _, l := range lines {
+		var (
+			name       string
+			inStock    models.Decimal
+			itemHUID   string
+			fractional bool
+			active     bool
+		)
+
+		row := tx.QueryRowContext(ctx, stmts.LOCK_ITEM, l.itemUID)
+		err = row.Scan(&name, &l.price, &inStock, &itemHUID, &l.muUID, &fractional, &active)
+		if err != nil {
+			tx.Rollback()
+			return "", dbError(err)
+		}
+
+		if !fractional && !l.quantity.IsWhole() {
+			tx.Rollback()
+			return "", models.ErrFractionalQuantity.Withf("%s", name)
+		}
+
+		if !active || l.quantity > inStock {
+			tx.Rollback()
+			return "", models.ErrOutOfStock.Withf("%s", name)
+		}
+
+		// Итог заказа считается в постгрес, но сначала убеждаемся, что он поместится в Decimal
+		lineTotal, err := l.price.Mul(l.quantity)
+		if err == nil {
+			total, err = total.Add(lineTotal)
+		}
+		if err != nil {
+			tx.Rollback()
+			return "", err
+		}
+
+		_, err = tx.ExecContext(ctx, stmts.TAKE_FROM_STOCK, l.quantity, l.itemUID)
+		if err != nil {
+			tx.Rollback()
+			return "", dbError(err)
+		}
+
+		// Остаток - часть товара, поэтому меняется и его версия (ETag)
+		err = touchHistory(ctx, tx, itemHUID, now)
+		if err != nil {
+			tx.Rollback()
+			return "", err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_HISTORY, huid.String(), now, nil, nil)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_ORDER, ouid.String(), uid, huid.String(), models.StatusCreated)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	tuid, _ := uuid.NewV6()
+	_, err = tx.ExecContext(ctx, stmts.INSERT_ORDER_TRANSITION, tuid.String(), ouid.String(), "", uid, now, models.StatusCreated)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	for _, l := range lines {
+		otiuid, _ := uuid.NewV6()
+
+		_, err = tx.ExecContext(ctx, stmts.INSERT_ORDER_LINE, otiuid.String(), ouid.String(), l.itemUID, l.quantity, l.muUID, l.price)
+		if err != nil {
+			tx.Rollback()
+			return "", dbError(err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.CLEAR_CART, uid)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	return ouid.String(), nil
+}
+
+// Get() - метод для получения заказа со строками и историей смены статусов
+func (o *OrderModel) Get(ctx context.Context, ouid string) (*models.OrderOutput, error) {
+	row := o.DB.QueryRowContext(ctx, stmts.GET_ORDER_BY_PK, ouid)
+	oo, err := scanOrder(row)
+	if err != nil {
+		return nil, dbError(err, models.ErrOrderNotFound)
+	}
+
+	rows, err := o.DB.QueryContext(ctx, stmts.GET_ORDER_LINES, ouid)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		l := &models.OrderLineOutput{}
+		err = rows.Scan(&l.ItemUID, &l.ShopUID, &l.OwnerUID, &l.Name, &l.Quantity, &l.MeasureUnit, &l.Price)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		l.LineTotal, err = l.Price.Mul(l.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		oo.Lines = append(oo.Lines, l)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	trows, err := o.DB.QueryContext(ctx, stmts.GET_ORDER_TRANSITIONS, ouid)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer trows.Close()
+
+	for trows.Next() {
+		t := &models.OrderTransitionOutput{}
+		err = trows.Scan(&t.From, &t.To, &t.ActorUID, &t.CreatedAt)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		oo.Transitions = append(oo.Transitions, t)
+	}
+	if err = trows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	return oo, nil
+}
+
+// GetList() - метод для получения списка заказов пользователя без строк
+func (o *OrderModel) GetList(ctx context.Context, uid string) ([]*models.OrderOutput, error) {
+	var orders []*models.OrderOutput
+
+	rows, err := o.DB.QueryContext(ctx, stmts.GET_ORDERS_BY_USER, uid)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		oo, err := scanOrder(rows)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		orders = append(orders, oo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	return orders, nil
+}
+
+/*
+Transition() - метод для смены статуса заказа от имени actorUID.
+Заказ блокируется на время транзакции, переход проверяется по models.CanTransition()
+и записывается в order_transitions. Когда товары не дошли до покупателя, они
+возвращаются на склад, см. returnsToStock().
+*/
+func (o *OrderModel) Transition(ctx context.Context, ouid, to, actorUID string) error {
+	var from, huid string
+
+	tuid, _ := uuid.NewV6()
+	now := time.Now()
+
+	tx, err := o.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	row := tx.QueryRowContext(ctx, stmts.LOCK_ORDER, ouid)
+	err = row.Scan(&from, &huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrOrderNotFound)
+	}
+
+	if !models.CanTransition(from, to) {
+		tx.Rollback()
+		return models.ErrBadTransition.Withf("from %s to %s", from, to)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.SET_ORDER_STATUS, to, ouid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_ORDER_TRANSITION, tuid.String(), ouid, from, actorUID, now, to)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	if returnsToStock(from, to) {
+		_, err = tx.ExecContext(ctx, stmts.RETURN_ORDER_TO_STOCK, ouid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+
+		// If-Match в контексте относится к заказу, поэтому товары отмечаются без проверки версии
+		_, err = tx.ExecContext(ctx, stmts.TOUCH_ORDER_ITEMS, now, ouid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	err = touchHistory(ctx, tx, huid, now)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
+}
+
+/*
+returnsToStock() - проверяет, что при смене статуса товары заказа возвращаются на склад:
+при отмене и при возврате денег за еще не отправленный заказ. После доставки товар у покупателя,
+и если его вернут, продавец сам оприходует его через пополнение остатка.
+*/
+func returnsToStock(from, to string) bool {
+	return to == models.StatusCancelled || (from == models.StatusPaid && to == models.StatusRefunded)
+}
+
+// scanOrder() - сканирует строку выборки get_order в структуру заказа
+func scanOrder(row interface{ Scan(...interface{}) error }) (*models.OrderOutput, error) {
+	var oo models.OrderOutput
+
+	err := row.Scan(
+		&oo.OrderUID,
+		&oo.UserUID,
+		&oo.Status,
+		&oo.HistoryUID,
+		&oo.CreatedAt,
+		&oo.UpdatedAt,
+		&oo.Total,
+	)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &oo, nil
+}
@@ -0,0 +1,318 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/internal/db/migrate"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// openTestDB() - открывает тестовую БД со схемой из миграций.
+// Без TEST_PSQL_DSN тесты моделей пропускаются, им нужен настоящий постгрес
+func openTestDB(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_PSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_PSQL_DSN is not set")
+	}
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if err = conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = migrate.New(conn).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+// insertTestUser() - создает пользователя с уникальным именем и отдает его ключ
+func insertTestUser(t *testing.T, conn *sql.DB, prefix string) string {
+	ctx := context.Background()
+	u, _ := uuid.NewV4()
+	name := fmt.Sprintf("%s-%s", prefix, u.String())
+
+	users := &UserModel{DB: conn}
+	err := users.Insert(ctx, &models.UserSignupInput{
+		Username: name,
+		Password: "hash",
+		Email:    name + "@mail.test",
+		Country:  "Afghanistan",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uo, err := users.Get(ctx, name, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return uo.UserUID
+}
+
+func TestCheckoutLastUnit(t *testing.T) {
+	const buyers = 5
+
+	ctx := context.Background()
+	conn := openTestDB(t)
+	u, _ := uuid.NewV4()
+
+	seller := insertTestUser(t, conn, "seller")
+	suid, err := (&ShopModel{DB: conn}).Insert(ctx, seller, &models.ShopInput{Name: "shop-" + u.String()[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := &ItemModel{DB: conn}
+	iuid, err := items.Insert(ctx, &models.ItemInput{
+		ShopUID: suid,
+		Name:    "The last one",
+		Vendor:  "TestVendor",
+		Price:   1999,
+		InStock: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pcs, err := (&UnitModel{DB: conn}).GetByName(ctx, models.DefaultMeasureUnit)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carts := &CartModel{DB: conn}
+	uids := make([]string, buyers)
+	for i := range uids {
+		uids[i] = insertTestUser(t, conn, "buyer")
+		if err = carts.Put(ctx, uids[i], iuid, 100, pcs.UUID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		wg      sync.WaitGroup
+		start   = make(chan struct{})
+		results = make([]error, buyers)
+		orders  = &OrderModel{DB: conn}
+	)
+
+	for i := range uids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			_, results[i] = orders.Checkout(ctx, uids[i])
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.True(t, errors.Is(err, models.ErrOutOfStock), err.Error())
+	}
+	assert.Equal(t, 1, succeeded)
+
+	io, err := items.Get(ctx, iuid)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "0.00", io.InStock.String())
+	}
+}
+
+func TestCheckoutEmptyCart(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	uid := insertTestUser(t, conn, "buyer")
+
+	_, err := (&OrderModel{DB: conn}).Checkout(ctx, uid)
+	assert.True(t, errors.Is(err, models.ErrEmptyCart))
+}
+
+func TestCheckoutFractional(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	u, _ := uuid.NewV4()
+
+	seller := insertTestUser(t, conn, "seller")
+	buyer := insertTestUser(t, conn, "buyer")
+
+	shops := &ShopModel{DB: conn}
+	suid, err := shops.Insert(ctx, seller, &models.ShopInput{Name: "shop-" + u.String()[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := &ItemModel{DB: conn}
+	pcsUID, err := items.Insert(ctx, &models.ItemInput{ShopUID: suid, Name: "By piece", Vendor: "TestVendor", Price: 100, InStock: 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kgUID, err := items.Insert(ctx, &models.ItemInput{ShopUID: suid, Name: "By weight", Vendor: "TestVendor", Price: 1000, InStock: 300, MeasureUnit: "kg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kg, err := (&UnitModel{DB: conn}).GetByName(ctx, "kg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carts := &CartModel{DB: conn}
+	orders := &OrderModel{DB: conn}
+
+	// Дробное количество штучного товара в обход хэндлера, да еще и в чужой единице
+	if err = carts.Put(ctx, buyer, pcsUID, 150, kg.UUID); err != nil {
+		t.Fatal(err)
+	}
+	_, err = orders.Checkout(ctx, buyer)
+	assert.True(t, errors.Is(err, models.ErrFractionalQuantity))
+
+	io, _ := items.Get(ctx, pcsUID)
+	assert.Equal(t, "3.00", io.InStock.String())
+
+	// Весовой товар списывается и оплачивается ровно в заказанном количестве
+	assert.NoError(t, carts.Clear(ctx, buyer))
+	if err = carts.Put(ctx, buyer, kgUID, 150, kg.UUID); err != nil {
+		t.Fatal(err)
+	}
+	ouid, err := orders.Checkout(ctx, buyer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	io, _ = items.Get(ctx, kgUID)
+	assert.Equal(t, "1.50", io.InStock.String())
+
+	oo, err := orders.Get(ctx, ouid)
+	if assert.NoError(t, err) && assert.Len(t, oo.Lines, 1) {
+		assert.Equal(t, "1.50", oo.Lines[0].Quantity.String())
+		assert.Equal(t, "kg", oo.Lines[0].MeasureUnit)
+		assert.Equal(t, "15.00", oo.Total.String())
+	}
+
+	// Товары удаленного магазина купить нельзя
+	if err = carts.Put(ctx, buyer, kgUID, 100, kg.UUID); err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, shops.Delete(ctx, suid))
+	_, err = orders.Checkout(ctx, buyer)
+	assert.True(t, errors.Is(err, models.ErrOutOfStock))
+}
+
+func TestOrderTransition(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	u, _ := uuid.NewV4()
+
+	seller := insertTestUser(t, conn, "seller")
+	buyer := insertTestUser(t, conn, "buyer")
+
+	suid, err := (&ShopModel{DB: conn}).Insert(ctx, seller, &models.ShopInput{Name: "shop-" + u.String()[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := &ItemModel{DB: conn}
+	iuid, err := items.Insert(ctx, &models.ItemInput{ShopUID: suid, Name: "Returned", Vendor: "TestVendor", Price: 100, InStock: 300, MeasureUnit: "kg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kg, err := (&UnitModel{DB: conn}).GetByName(ctx, "kg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = (&CartModel{DB: conn}).Put(ctx, buyer, iuid, 150, kg.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	created, _ := items.Get(ctx, iuid)
+
+	orders := &OrderModel{DB: conn}
+	ouid, err := orders.Checkout(ctx, buyer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	io, _ := items.Get(ctx, iuid)
+	assert.Equal(t, "1.50", io.InStock.String())
+	assert.NotEqual(t, models.Version(created.CreatedAt, created.UpdatedAt), models.Version(io.CreatedAt, io.UpdatedAt))
+	checkedOut := models.Version(io.CreatedAt, io.UpdatedAt)
+
+	err = orders.Transition(ctx, ouid, models.StatusShipped, seller)
+	assert.True(t, errors.Is(err, models.ErrBadTransition))
+
+	assert.NoError(t, orders.Transition(ctx, ouid, models.StatusPaid, seller))
+	assert.NoError(t, orders.Transition(ctx, ouid, models.StatusCancelled, buyer))
+
+	io, _ = items.Get(ctx, iuid)
+	assert.Equal(t, "3.00", io.InStock.String())
+	assert.NotEqual(t, checkedOut, models.Version(io.CreatedAt, io.UpdatedAt))
+
+	// Деньги за неотправленный заказ возвращаются вместе с товаром на склад
+	if err = (&CartModel{DB: conn}).Put(ctx, buyer, iuid, 100, kg.UUID); err != nil {
+		t.Fatal(err)
+	}
+	refunded, err := orders.Checkout(ctx, buyer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, orders.Transition(ctx, refunded, models.StatusPaid, seller))
+	assert.NoError(t, orders.Transition(ctx, refunded, models.StatusRefunded, seller))
+
+	io, _ = items.Get(ctx, iuid)
+	assert.Equal(t, "3.00", io.InStock.String())
+
+	oo, err := orders.Get(ctx, ouid)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.StatusCancelled, oo.Status)
+		assert.Equal(t, "1.50", oo.Total.String())
+		if assert.Len(t, oo.Transitions, 3) {
+			assert.Equal(t, "", oo.Transitions[0].From)
+			assert.Equal(t, models.StatusCreated, oo.Transitions[0].To)
+			assert.Equal(t, models.StatusPaid, oo.Transitions[2].From)
+			assert.Equal(t, buyer, oo.Transitions[2].ActorUID)
+		}
+	}
+}
+
+func TestReturnsToStock(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.StatusCreated, models.StatusCancelled, true},
+		{models.StatusPaid, models.StatusCancelled, true},
+		// refund before shipping, goods are still in the warehouse
+		{models.StatusPaid, models.StatusRefunded, true},
+		// refund after delivery, goods are with the buyer
+		{models.StatusDelivered, models.StatusRefunded, false},
+		{models.StatusPaid, models.StatusShipped, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, returnsToStock(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+)
+
+// RevocationModel - хранилище отозванных токенов доступа в постгрес
+type RevocationModel struct {
+	DB *sql.DB
+}
+
+/*
+Revoke() - метод для отзыва одного токена доступа по его jti.
+Заодно удаляет записи об уже истекших токенах: их не примет проверка подписи, и хранить их незачем
+*/
+func (r *RevocationModel) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	_, err := r.Purge(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = r.DB.ExecContext(ctx, stmts.INSERT_REVOKED_TOKEN, jti, expiresAt)
+	return dbError(err)
+}
+
+// RevokeAll() - метод для отзыва всех токенов пользователя, выпущенных не позже before (с точностью до микросекунды)
+func (r *RevocationModel) RevokeAll(ctx context.Context, uid string, before time.Time) error {
+	_, err := r.DB.ExecContext(ctx, stmts.UPSERT_USER_REVOKE, uid, before)
+	return dbError(err)
+}
+
+// IsRevoked() - метод для проверки, отозван ли токен лично или вместе со всеми токенами пользователя.
+// Записи об истекших токенах не учитываются, даже если их еще не удалили
+func (r *RevocationModel) IsRevoked(ctx context.Context, jti, uid string, issuedAt time.Time) (bool, error) {
+	var revoked bool
+
+	row := r.DB.QueryRowContext(ctx, stmts.IS_TOKEN_REVOKED, jti, uid, issuedAt, time.Now())
+	err := row.Scan(&revoked)
+	if err != nil {
+		return false, dbError(err)
+	}
+
+	return revoked, nil
+}
+
+// Purge() - метод для удаления отозванных токенов, истекших до before: проверять их уже незачем
+func (r *RevocationModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx, stmts.PURGE_REVOKED_TOKENS, before)
+	if err != nil {
+		return 0, dbError(err)
+	}
+
+	return res.RowsAffected()
+}
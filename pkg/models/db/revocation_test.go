@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokeExpired(t *testing.T) {
+	ctx := context.Background()
+	conn := openTestDB(t)
+	revocations := &RevocationModel{DB: conn}
+
+	expired, _ := uuid.NewV4()
+	active, _ := uuid.NewV4()
+	uid := insertTestUser(t, conn, "revoked")
+
+	assert.NoError(t, revocations.Revoke(ctx, expired.String(), time.Now().Add(-time.Minute)))
+
+	// Запись об истекшем токене не учитывается
+	revoked, err := revocations.IsRevoked(ctx, expired.String(), uid, time.Now())
+	if assert.NoError(t, err) {
+		assert.False(t, revoked)
+	}
+
+	// Следующий отзыв удаляет истекшие записи
+	assert.NoError(t, revocations.Revoke(ctx, active.String(), time.Now().Add(time.Minute)))
+
+	var left int
+	err = conn.QueryRow("SELECT count(*) FROM revoked_tokens WHERE jti = $1", expired.String()).Scan(&left)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, left)
+	}
+
+	revoked, err = revocations.IsRevoked(ctx, active.String(), uid, time.Now())
+	if assert.NoError(t, err) {
+		assert.True(t, revoked)
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// RoleModel - модель связи пользователей и ролей
+type RoleModel struct {
+	DB *sql.DB
+}
+
+/*
+Grant() - метод для выдачи роли пользователю.
+Роли входят в профиль, поэтому выдача отмечается в истории пользователя и меняет его версию.
+*/
+func (r *RoleModel) Grant(ctx context.Context, uid string, role string) error {
+	return r.change(ctx, stmts.GRANT_USER_ROLE, uid, role)
+}
+
+// Revoke() - метод для отзыва роли у пользователя, как и выдача, меняет версию пользователя
+func (r *RoleModel) Revoke(ctx context.Context, uid string, role string) error {
+	return r.change(ctx, stmts.REVOKE_USER_ROLE, uid, role)
+}
+
+// change() - общая часть Grant() и Revoke(): история трогается, только если связь действительно изменилась
+func (r *RoleModel) change(ctx context.Context, stmt, uid, role string) error {
+	ruid, err := r.getPK(ctx, role)
+	if err != nil {
+		return dbError(err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, stmt, uid, ruid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	if n > 0 {
+		_, err = tx.ExecContext(ctx, stmts.UPDATE_USER_HISTORY, time.Now(), uid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	return dbError(tx.Commit())
+}
+
+// getPK() - метод, который достает ключ роли по ее названию
+func (r *RoleModel) getPK(ctx context.Context, role string) (string, error) {
+	var ruid string
+
+	row := r.DB.QueryRowContext(ctx, stmts.GET_ROLE_PK, role)
+	err := row.Scan(&ruid)
+	if err != nil {
+		return "", dbError(err, models.ErrUnknownRole)
+	}
+
+	return ruid, nil
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// ShopModel - модель сущности shops
+type ShopModel struct {
+	DB *sql.DB
+}
+
+// Insert() - метод для создания нового магазина пользователя, отдает ключ магазина
+func (s *ShopModel) Insert(ctx context.Context, uid string, input *models.ShopInput) (string, error) {
+	huid, _ := uuid.NewV6()
+	suid, _ := uuid.NewV6()
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_HISTORY, huid.String(), time.Now(), nil, nil)
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_SHOP, suid.String(), input.Name, input.Description, uid, huid.String())
+	if err != nil {
+		tx.Rollback()
+		return "", dbError(err, models.ErrShopExists)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", dbError(err)
+	}
+
+	return suid.String(), nil
+}
+
+// Get() - метод для получения данных о магазине по ключу
+func (s *ShopModel) Get(ctx context.Context, suid string) (*models.ShopOutput, error) {
+	row := s.DB.QueryRowContext(ctx, stmts.GET_SHOP_BY_PK, suid)
+
+	so, err := scanShop(row)
+	if err != nil {
+		return nil, dbError(err, models.ErrShopNotFound)
+	}
+
+	return so, nil
+}
+
+// GetList() - метод для получения списка действующих магазинов, всех или только одного владельца
+func (s *ShopModel) GetList(ctx context.Context, ownerUID string) ([]*models.ShopOutput, error) {
+	var (
+		shops []*models.ShopOutput
+		rows  *sql.Rows
+		err   error
+	)
+
+	if ownerUID == "" {
+		rows, err = s.DB.QueryContext(ctx, stmts.GET_SHOPS)
+	} else {
+		rows, err = s.DB.QueryContext(ctx, stmts.GET_SHOPS_BY_OWN, ownerUID)
+	}
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		so, err := scanShop(rows)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		shops = append(shops, so)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	return shops, nil
+}
+
+// Update() - метод для обновления некоторых данных магазина
+func (s *ShopModel) Update(ctx context.Context, suid string, input *models.ShopUpdateInput) error {
+	var (
+		huid    string
+		counter int
+	)
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if input.Name != "" {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE shops SET name = $1 WHERE shop_uid = $2", input.Name, suid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err, models.ErrShopExists)
+		}
+	}
+
+	if input.Description != "" {
+		counter++
+		_, err := tx.ExecContext(ctx, "UPDATE shops SET description = $1 WHERE shop_uid = $2", input.Description, suid)
+		if err != nil {
+			tx.Rollback()
+			return dbError(err)
+		}
+	}
+
+	if counter <= 0 {
+		tx.Rollback()
+		return models.ErrNothingToUpdate
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM shops WHERE shop_uid = $1", suid)
+	err = row.Scan(&huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrShopNotFound)
+	}
+
+	err = touchHistory(ctx, tx, huid, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
+}
+
+// Delete() - метод для фейкового удаления магазина через поле deleted_at в его истории
+func (s *ShopModel) Delete(ctx context.Context, suid string) error {
+	var huid string
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM shops WHERE shop_uid = $1", suid)
+	err = row.Scan(&huid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err, models.ErrShopNotFound)
+	}
+
+	err = deleteHistory(ctx, tx, huid, time.Now())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return dbError(tx.Commit())
+}
+
+// scanShop() - сканирует строку выборки get_shop в структуру магазина
+func scanShop(row interface{ Scan(...interface{}) error }) (*models.ShopOutput, error) {
+	var so models.ShopOutput
+
+	err := row.Scan(
+		&so.ShopUID,
+		&so.Name,
+		&so.Description,
+		&so.OwnerUID,
+		&so.HistoryUID,
+		&so.CreatedAt,
+		&so.UpdatedAt,
+		&so.DeletedAt,
+	)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &so, nil
+}
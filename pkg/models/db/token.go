@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// TokenModel - модель сущности refresh_tokens
+type TokenModel struct {
+	DB *sql.DB
+}
+
+/*
+Insert() - метод для сохранения хэша нового refresh-токена.
+Пустой familyUID означает начало новой цепочки (семейства) токенов, то есть новый логин.
+*/
+func (t *TokenModel) Insert(ctx context.Context, userUID, familyUID, hash string, expiresAt time.Time) error {
+	tuid, _ := uuid.NewV6()
+
+	if familyUID == "" {
+		fuid, _ := uuid.NewV6()
+		familyUID = fuid.String()
+	}
+
+	_, err := t.DB.ExecContext(ctx, stmts.INSERT_REFRESH_TOKEN, tuid.String(), familyUID, userUID, hash, time.Now(), expiresAt)
+	return dbError(err)
+}
+
+// Get() - метод для получения данных о refresh-токене по его хэшу
+func (t *TokenModel) Get(ctx context.Context, hash string) (*models.RefreshTokenOutput, error) {
+	var rt models.RefreshTokenOutput
+
+	row := t.DB.QueryRowContext(ctx, stmts.GET_REFRESH_TOKEN, hash)
+	err := row.Scan(
+		&rt.TokenUID,
+		&rt.FamilyUID,
+		&rt.UserUID,
+		&rt.ExpiresAt,
+		&rt.UsedAt,
+		&rt.RevokedAt,
+	)
+	if err != nil {
+		return nil, dbError(err, models.ErrBadRefreshToken)
+	}
+
+	return &rt, nil
+}
+
+/*
+Rotate() - метод для обмена refresh-токена на новый в том же семействе.
+Старый токен помечается использованным условным апдейтом, поэтому из двух
+параллельных обменов одного и того же токена успешным будет только один,
+второй получит models.ErrTokenReused.
+*/
+func (t *TokenModel) Rotate(ctx context.Context, old *models.RefreshTokenOutput, hash string, expiresAt time.Time) error {
+	tuid, _ := uuid.NewV6()
+
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, stmts.USE_REFRESH_TOKEN, time.Now(), old.TokenUID)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return models.ErrTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, stmts.INSERT_REFRESH_TOKEN, tuid.String(), old.FamilyUID, old.UserUID, hash, time.Now(), expiresAt)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
+}
+
+// RevokeFamily() - метод для отзыва всей цепочки токенов, например при повторном использовании
+func (t *TokenModel) RevokeFamily(ctx context.Context, familyUID string) error {
+	_, err := t.DB.ExecContext(ctx, stmts.REVOKE_TOKEN_FAMILY, time.Now(), familyUID)
+	return dbError(err)
+}
+
+// RevokeUser() - метод для отзыва всех refresh-токенов пользователя
+func (t *TokenModel) RevokeUser(ctx context.Context, userUID string) error {
+	_, err := t.DB.ExecContext(ctx, stmts.REVOKE_USER_TOKENS, time.Now(), userUID)
+	return dbError(err)
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/JohanVong/online_bazaar/internal/db/stmts"
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// UnitModel - модель сущности measure_units
+type UnitModel struct {
+	DB *sql.DB
+}
+
+// GetList() - метод, который достает список всех единиц измерения
+func (u *UnitModel) GetList(ctx context.Context) ([]*models.MeasureUnitOutput, error) {
+	var units []*models.MeasureUnitOutput
+
+	rows, err := u.DB.QueryContext(ctx, stmts.GET_UNITS)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		mu := &models.MeasureUnitOutput{}
+		err = rows.Scan(&mu.UUID, &mu.Unit, &mu.Fractional)
+		if err != nil {
+			return nil, dbError(err)
+		}
+		units = append(units, mu)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, dbError(err)
+	}
+
+	return units, nil
+}
+
+// GetByName() - метод, который достает единицу измерения по ее названию
+func (u *UnitModel) GetByName(ctx context.Context, name string) (*models.MeasureUnitOutput, error) {
+	var mu models.MeasureUnitOutput
+
+	row := u.DB.QueryRowContext(ctx, stmts.GET_UNIT_BY_NAME, name)
+	err := row.Scan(&mu.UUID, &mu.Unit, &mu.Fractional)
+	if err != nil {
+		return nil, dbError(err, models.ErrUnknownUnit)
+	}
+
+	return &mu, nil
+}
@@ -1,11 +1,14 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/lib/pq"
 
 	"github.com/JohanVong/online_bazaar/internal/db/stmts"
 	"github.com/JohanVong/online_bazaar/pkg/models"
@@ -17,48 +20,59 @@ type UserModel struct {
 }
 
 // Insert() - метод для создания новой записи о пользователе
-func (u *UserModel) Insert(input *models.UserSignupInput) error {
+func (u *UserModel) Insert(ctx context.Context, input *models.UserSignupInput) error {
 	var (
 		cuid string
+		ruid string
 		err  error
 	)
 
 	huid, _ := uuid.NewV6()
 	uid, _ := uuid.NewV6()
 
-	tx, err := u.DB.Begin()
+	tx, err := u.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
-	row := tx.QueryRow(stmts.GET_COUNTRY_PK, input.Country)
+	row := tx.QueryRowContext(ctx, stmts.GET_COUNTRY_PK, input.Country)
 	err = row.Scan(&cuid)
 	if err != nil {
 		tx.Rollback()
-		if errors.Is(err, sql.ErrNoRows) {
-			return errors.New("Provided country does not exist")
-		}
-		return err
+		return dbError(err, models.ErrUnknownCountry)
 	}
 
-	_, err = tx.Exec(stmts.INSERT_HISTORY, huid.String(), time.Now(), nil, nil)
+	_, err = tx.ExecContext(ctx, stmts.INSERT_HISTORY, huid.String(), time.Now(), nil, nil)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err)
 	}
 
-	_, err = tx.Exec(stmts.INSERT_USER, uid.String(), input.Username, input.Password, input.Email, input.Phone, cuid, huid.String())
+	_, err = tx.ExecContext(ctx, stmts.INSERT_USER, uid.String(), input.Username, input.Password, input.Email, input.Phone, cuid, huid.String())
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err, models.ErrUserExists)
+	}
+
+	// Каждый новый пользователь по умолчанию покупатель
+	row = tx.QueryRowContext(ctx, stmts.GET_ROLE_PK, models.RoleBuyer)
+	err = row.Scan(&ruid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
 	}
 
-	tx.Commit()
-	return nil
+	_, err = tx.ExecContext(ctx, stmts.GRANT_USER_ROLE, uid.String(), ruid)
+	if err != nil {
+		tx.Rollback()
+		return dbError(err)
+	}
+
+	return dbError(tx.Commit())
 }
 
 // Get() - метод для получения данных о пользователе по ключу или юзернейму
-func (u *UserModel) Get(key string, byPK bool) (*models.UserOutput, error) {
+func (u *UserModel) Get(ctx context.Context, key string, byPK bool) (*models.UserOutput, error) {
 	var (
 		stmt string
 		uodb models.UserOutput
@@ -71,7 +85,7 @@ func (u *UserModel) Get(key string, byPK bool) (*models.UserOutput, error) {
 		stmt = stmts.GET_USER_BY_NAME
 	}
 
-	row := u.DB.QueryRow(stmt, key)
+	row := u.DB.QueryRowContext(ctx, stmt, key)
 	err = row.Scan(
 		&uodb.UserUID,
 		&uodb.Username,
@@ -84,104 +98,104 @@ func (u *UserModel) Get(key string, byPK bool) (*models.UserOutput, error) {
 		&uodb.CreatedAt,
 		&uodb.UpdatedAt,
 		&uodb.DeletedAt,
+		pq.Array(&uodb.Roles),
 	)
 	if err != nil {
-		return nil, err
+		return nil, dbError(err, models.ErrUserNotFound)
 	}
 
 	return &uodb, nil
 }
 
-// Update() - метод для обновления некоторых данных в записи пользователя
-func (u *UserModel) Update(uid string, input *models.UserUpdateInput) error {
+/*
+Update() - метод для частичного обновления записи пользователя одним запросом UPDATE:
+в SET попадают только пришедшие поля, null в Phone записывается как NULL
+*/
+func (u *UserModel) Update(ctx context.Context, uid string, input *models.UserUpdateInput) error {
 	var (
-		huid    string
-		counter int
+		huid string
+		sets []string
+		args []interface{}
 	)
 
-	tx, err := u.DB.Begin()
-	if err != nil {
-		return err
-	}
-
-	if input.Email != "" {
-		counter++
-		_, err := tx.Exec("UPDATE users SET email = $1 WHERE user_uid = $2", input.Email, uid)
-		if err != nil {
-			tx.Rollback()
-			return err
+	for _, f := range []struct {
+		column string
+		value  models.NullString
+	}{
+		{"email", input.Email},
+		{"phone", input.Phone},
+		{"country_uid", input.Country},
+	} {
+		if f.value.Set {
+			args = append(args, f.value)
+			sets = append(sets, fmt.Sprintf("%s = $%d", f.column, len(args)))
 		}
 	}
 
-	if input.Phone != "" {
-		counter++
-		_, err := tx.Exec("UPDATE users SET phone = $1 WHERE user_uid = $2", input.Phone, uid)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-
-	if input.Country != "" {
-		counter++
-		_, err := tx.Exec("UPDATE users SET country_uid = $1 WHERE user_uid = $2", input.Country, uid)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
+	if len(sets) == 0 {
+		return models.ErrNothingToUpdate
 	}
 
-	if counter <= 0 {
-		return errors.New("Nothing to update")
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
 	}
 
-	row := tx.QueryRow("SELECT history_uid FROM users WHERE user_uid = $1", uid)
+	args = append(args, uid)
+	row := tx.QueryRowContext(ctx, fmt.Sprintf(stmts.UPDATE_USER, strings.Join(sets, ", "), len(args)), args...)
 	err = row.Scan(&huid)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err, models.ErrUserNotFound, models.ErrUserExists)
 	}
 
-	_, err = tx.Exec(stmts.UPDATE_HISTORY, time.Now(), huid)
+	err = touchHistory(ctx, tx, huid, time.Now())
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err)
 	}
 
-	tx.Commit()
-	return nil
+	return dbError(tx.Commit())
 }
 
 // UpdatePassword() - метод для обновления пароля у записи пользователя
-func (u *UserModel) UpdatePassword(uid string, input *models.UpdateUserPasswordInput) error {
+func (u *UserModel) UpdatePassword(ctx context.Context, uid string, input *models.UpdateUserPasswordInput) error {
 	var huid string
 
-	tx, err := u.DB.Begin()
+	tx, err := u.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
-	_, err = tx.Exec("UPDATE users SET pw_hash = $1 WHERE user_uid = $2", input.NewPassword, uid)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET pw_hash = $1 WHERE user_uid = $2", input.NewPassword, uid)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err)
 	}
 
-	row := tx.QueryRow("SELECT history_uid FROM users WHERE user_uid = $1", uid)
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM users WHERE user_uid = $1", uid)
 	err = row.Scan(&huid)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err, models.ErrUserNotFound)
 	}
 
-	_, err = tx.Exec(stmts.UPDATE_HISTORY, time.Now(), huid)
+	err = touchHistory(ctx, tx, huid, time.Now())
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err)
 	}
 
-	tx.Commit()
-	return nil
+	return dbError(tx.Commit())
+}
+
+/*
+UpdateHash() - метод для тихой замены хэша пароля (например, при смене алгоритма).
+В отличие от UpdatePassword() не трогает историю: пароль по сути не менялся.
+*/
+func (u *UserModel) UpdateHash(ctx context.Context, uid string, hash string) error {
+	_, err := u.DB.ExecContext(ctx, stmts.UPDATE_USER_HASH, hash, uid)
+	return dbError(err)
 }
 
 /*
@@ -190,27 +204,26 @@ Delete() - метод для фейкового удаления пользов
 При следующих запросах миддлвер видит это и блокирует доступ к пользователю,
 но запись с его данными остается в БД.
 */
-func (u *UserModel) Delete(uid string) error {
+func (u *UserModel) Delete(ctx context.Context, uid string) error {
 	var huid string
 
-	tx, err := u.DB.Begin()
+	tx, err := u.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return dbError(err)
 	}
 
-	row := tx.QueryRow("SELECT history_uid FROM users WHERE user_uid = $1", uid)
+	row := tx.QueryRowContext(ctx, "SELECT history_uid FROM users WHERE user_uid = $1", uid)
 	err = row.Scan(&huid)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return dbError(err, models.ErrUserNotFound)
 	}
 
-	_, err = tx.Exec(stmts.DELETE_HISTORY, time.Now(), huid)
+	err = deleteHistory(ctx, tx, huid, time.Now())
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	tx.Commit()
-	return nil
+	return dbError(tx.Commit())
 }
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrBadDecimal - ошибка разбора десятичного числа
+	ErrBadDecimal = newError(KindInvalid, "bad_decimal", "Wrong decimal format")
+	// ErrDecimalOverflow - результат арифметики не помещается в Decimal
+	ErrDecimalOverflow = newError(KindInvalid, "decimal_overflow", "Amount is too large")
+)
+
+/*
+Decimal - точное десятичное число с двумя знаками после запятой, хранится в сотых.
+Соответствует numeric(19, 2) и numeric(10, 2) в БД, поэтому цены и количества
+не проходят через float64 и не теряют копейки.
+В JSON пишется строкой "12.34", читается как из строки, так и из числа.
+*/
+type Decimal int64
+
+// ParseDecimal() - разбирает строку вида "12", "12.3" или "-12.34"
+func ParseDecimal(s string) (Decimal, error) {
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+
+	if whole == "" || len(frac) > 2 || (strings.Contains(s, ".") && frac == "") {
+		return 0, ErrBadDecimal
+	}
+
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, ErrBadDecimal
+		}
+	}
+
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, ErrBadDecimal
+	}
+
+	if neg {
+		v = -v
+	}
+
+	return Decimal(v), nil
+}
+
+// String() - отдает число в виде "12.34"
+func (d Decimal) String() string {
+	sign := ""
+	v := int64(d)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// IsWhole() - проверяет, что у числа нет дробной части
+func (d Decimal) IsWhole() bool {
+	return d%100 == 0
+}
+
+/*
+Mul() - умножает два числа с округлением до сотых (половина вверх по модулю).
+Произведение считается в 128 битах, и если результат не помещается в Decimal,
+отдается ErrDecimalOverflow, а не тихо переполненное значение.
+*/
+func (d Decimal) Mul(o Decimal) (Decimal, error) {
+	hi, lo := bits.Mul64(abs(d), abs(o))
+	lo, carry := bits.Add64(lo, 50, 0)
+	hi += carry
+	if hi >= 100 {
+		return 0, ErrDecimalOverflow
+	}
+
+	q, _ := bits.Div64(hi, lo, 100)
+	if q > math.MaxInt64 {
+		return 0, ErrDecimalOverflow
+	}
+
+	if (d < 0) != (o < 0) {
+		return -Decimal(q), nil
+	}
+
+	return Decimal(q), nil
+}
+
+// Add() - складывает два числа, при переполнении отдает ErrDecimalOverflow
+func (d Decimal) Add(o Decimal) (Decimal, error) {
+	sum := d + o
+	if (o > 0 && sum < d) || (o < 0 && sum > d) {
+		return 0, ErrDecimalOverflow
+	}
+
+	return sum, nil
+}
+
+// abs() - модуль числа в сотых, без переполнения и для самого маленького int64
+func abs(d Decimal) uint64 {
+	if d < 0 {
+		return uint64(-int64(d))
+	}
+
+	return uint64(d)
+}
+
+// MarshalJSON() - пишет число в JSON строкой, чтобы клиенты не теряли точность
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON() - читает число из JSON строки или JSON числа
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "null" {
+		return nil
+	}
+
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
+
+// UnmarshalParam() - читает число из параметров запроса (query, path, form)
+func (d *Decimal) UnmarshalParam(param string) error {
+	return d.scanString(param)
+}
+
+// Scan() - читает numeric из БД
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	case int64:
+		*d = Decimal(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("Can not scan %T into Decimal", src)
+	}
+}
+
+// Value() - отдает число в БД строкой, которую постгрес приводит к numeric без потерь
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) scanString(s string) error {
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+
+	*d = v
+	return nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Decimal
+		wantErr error
+	}{
+		{"12", 1200, nil},
+		{"12.3", 1230, nil},
+		{"12.34", 1234, nil},
+		{"0.01", 1, nil},
+		{"-7.50", -750, nil},
+		{"12.345", 0, ErrBadDecimal},
+		{"12.", 0, ErrBadDecimal},
+		{".5", 0, ErrBadDecimal},
+		{"1e3", 0, ErrBadDecimal},
+		{"", 0, ErrBadDecimal},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDecimal(tt.input)
+		assert.Equal(t, tt.wantErr, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		Price    Decimal
+		Quantity Decimal
+	}
+
+	err := json.Unmarshal([]byte(`{"Price":"19.99","Quantity":2.5}`), &v)
+	if assert.NoError(t, err) {
+		assert.Equal(t, Decimal(1999), v.Price)
+		assert.Equal(t, Decimal(250), v.Quantity)
+	}
+
+	b, err := json.Marshal(v)
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"Price":"19.99","Quantity":"2.50"}`, string(b))
+	}
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Price":"19.999"}`), &v))
+}
+
+func TestDecimalMul(t *testing.T) {
+	tests := []struct {
+		a, b    Decimal
+		want    string
+		wantErr error
+	}{
+		{1999, 200, "39.98", nil},
+		{10, 333, "0.33", nil},
+		{133, 300, "3.99", nil},
+		{2, 25, "0.01", nil},
+		{-1, 50, "-0.01", nil},
+		{-1999, -200, "39.98", nil},
+		// максимальные цена и количество из валидации ItemInput и CartItemInput
+		{99999999999999999, 9999999999, "0.00", ErrDecimalOverflow},
+		{math.MaxInt64, 100, "92233720368547758.07", nil},
+		{math.MaxInt64, 101, "0.00", ErrDecimalOverflow},
+		{math.MinInt64, -100, "0.00", ErrDecimalOverflow},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.a.Mul(tt.b)
+		assert.Equal(t, tt.wantErr, err, "%d * %d", tt.a, tt.b)
+		assert.Equal(t, tt.want, got.String(), "%d * %d", tt.a, tt.b)
+	}
+}
+
+func TestDecimalAdd(t *testing.T) {
+	sum, err := Decimal(150).Add(-200)
+	assert.NoError(t, err)
+	assert.Equal(t, "-0.50", sum.String())
+
+	_, err = Decimal(math.MaxInt64).Add(1)
+	assert.Equal(t, ErrDecimalOverflow, err)
+
+	_, err = Decimal(math.MinInt64).Add(-1)
+	assert.Equal(t, ErrDecimalOverflow, err)
+}
@@ -0,0 +1,98 @@
+package models
+
+import "fmt"
+
+// Kind - вид доменной ошибки, по нему приложение выбирает HTTP статус
+type Kind int
+
+// Виды доменных ошибок
+const (
+	KindInternal Kind = iota
+	KindInvalid
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPrecondition
+)
+
+/*
+Error - доменная ошибка: вид, стабильный машиночитаемый код и текст для клиента.
+Код служит и ключом перевода, Message - текст по умолчанию на английском.
+Detail - уточнение без перевода (имя товара, статусы заказа), оно дописывается к тексту.
+Исходная причина (например, ошибка постгрес) хранится в Err и попадает только в логи.
+*/
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Detail  string
+	Err     error
+}
+
+// Error() - полный текст ошибки вместе с уточнением и причиной, для логов
+func (e *Error) Error() string {
+	msg := e.Message
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap() - отдает причину ошибки для errors.Is и errors.As
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is() - доменные ошибки равны, если совпадают их коды, поэтому errors.Is работает и с копиями
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap() - копия ошибки с внутренней причиной
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// Withf() - копия ошибки с уточнением для клиента, код не меняется
+func (e *Error) Withf(format string, args ...interface{}) *Error {
+	c := *e
+	c.Detail = fmt.Sprintf(format, args...)
+	return &c
+}
+
+// codes - коды всех объявленных доменных ошибок
+var codes []string
+
+// Codes() - коды всех доменных ошибок, например, чтобы проверить полноту переводов
+func Codes() []string {
+	return append([]string(nil), codes...)
+}
+
+func newError(kind Kind, code, message string) *Error {
+	codes = append(codes, code)
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+// Общие ошибки, не привязанные к конкретной сущности
+var (
+	// ErrNotFound - запись не найдена
+	ErrNotFound = newError(KindNotFound, "not_found", "Record not found")
+	// ErrAlreadyExists - нарушено ограничение уникальности
+	ErrAlreadyExists = newError(KindConflict, "already_exists", "Record already exists")
+	// ErrBadReference - ссылка на несуществующую запись
+	ErrBadReference = newError(KindInvalid, "bad_reference", "Referenced record does not exist")
+	// ErrBadIdentifier - ключ записи в неверном формате
+	ErrBadIdentifier = newError(KindInvalid, "bad_identifier", "Malformed identifier")
+	// ErrNothingToUpdate - в запросе на обновление нет ни одного поля
+	ErrNothingToUpdate = newError(KindInvalid, "nothing_to_update", "Nothing to update")
+	// ErrAccessDenied - у пользователя нет прав на действие
+	ErrAccessDenied = newError(KindForbidden, "access_denied", "Access denied")
+)
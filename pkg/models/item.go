@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+var (
+	// ErrItemNotFound - товара нет
+	ErrItemNotFound = newError(KindNotFound, "item_not_found", "Item not found")
+	// ErrItemDeleted - товар удален
+	ErrItemDeleted = newError(KindNotFound, "item_deleted", "Item was deleted")
+	// ErrNegativeStock - списание больше остатка
+	ErrNegativeStock = newError(KindConflict, "negative_stock", "Stock can not become negative")
+	// ErrBadCursor - курсор пагинации поврежден или не подходит к запросу
+	ErrBadCursor = newError(KindInvalid, "bad_cursor", "Wrong cursor")
+	// ErrBadPriceRange - нижняя граница цены больше верхней
+	ErrBadPriceRange = newError(KindInvalid, "bad_price_range", "price_min is greater than price_max")
+)
+
+// ItemInput - структура запроса в апи для создания товара.
+// Ограничения повторяют CHECK и размеры колонок таблицы items.
+// Если единица измерения не указана, товар продается поштучно (pcs)
+type ItemInput struct {
+	ShopUID     string  `json:"ShopUID" validate:"required"`
+	Name        string  `json:"Name" validate:"required,max=255"`
+	Vendor      string  `json:"Vendor" validate:"required,max=60"`
+	Price       Decimal `json:"Price" validate:"gt=0,lt=100000000000000000"`
+	Description string  `json:"Description"`
+	InStock     Decimal `json:"InStock" validate:"min=0,lt=1000000000000"`
+	MeasureUnit string  `json:"MeasureUnit" validate:"max=30"`
+}
+
+// ItemUpdateInput - структура запроса в апи для обновления некоторых данных товара
+type ItemUpdateInput struct {
+	Name        string  `json:"Name" validate:"max=255"`
+	Vendor      string  `json:"Vendor" validate:"max=60"`
+	Price       Decimal `json:"Price" validate:"omitempty,gt=0,lt=100000000000000000"`
+	Description string  `json:"Description"`
+}
+
+// ItemRestockInput - структура запроса в апи для изменения остатка товара.
+// Delta прибавляется к остатку, отрицательное значение - списание
+type ItemRestockInput struct {
+	Delta Decimal `json:"Delta" validate:"required,gt=-1000000000000,lt=1000000000000"`
+}
+
+// ItemOutput - вью апи для получения данных о товаре
+type ItemOutput struct {
+	ItemUID     string
+	ShopUID     string
+	OwnerUID    string `json:"-"`
+	Name        string
+	Vendor      string
+	Price       Decimal
+	Description string
+	InStock     Decimal
+	MeasureUnit string
+	HistoryUID  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   time.Time
+	Rating      Decimal `json:",omitempty"`
+}
+
+// ItemSearchInput - параметры запроса в апи для поиска товаров
+type ItemSearchInput struct {
+	Query    string  `query:"q" validate:"max=255"`
+	PriceMin Decimal `query:"price_min" validate:"min=0"`
+	PriceMax Decimal `query:"price_max" validate:"min=0"`
+	ShopUID  string  `query:"shop"`
+	Vendor   string  `query:"vendor" validate:"max=60"`
+	InStock  bool    `query:"in_stock"`
+	Sort     string  `query:"sort" validate:"omitempty,oneof=price_asc price_desc newest rating"`
+	Cursor   string  `query:"cursor"`
+	Limit    int     `query:"limit" validate:"min=0,max=100"`
+}
+
+// ItemSearchOutput - страница результатов поиска товаров.
+// NextCursor передается в следующий запрос, пустой - страниц больше нет
+type ItemSearchOutput struct {
+	Items      []*ItemOutput
+	NextCursor string `json:",omitempty"`
+}
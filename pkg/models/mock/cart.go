@@ -0,0 +1,122 @@
+package mock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// CartModel - хранит корзины в памяти поверх фикстур товаров
+type CartModel struct {
+	mu    sync.Mutex
+	carts map[string][]*models.CartLineOutput
+}
+
+func (c *CartModel) Get(ctx context.Context, uid string) (*models.CartOutput, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var co models.CartOutput
+	for _, l := range c.carts[uid] {
+		line := *l
+		co.Lines = append(co.Lines, &line)
+	}
+
+	if err := co.Compute(); err != nil {
+		return nil, err
+	}
+
+	return &co, nil
+}
+
+func (c *CartModel) Put(ctx context.Context, uid, iuid string, quantity models.Decimal, muUID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.put(uid, iuid, quantity, muUID, false)
+}
+
+func (c *CartModel) Add(ctx context.Context, uid, iuid string, quantity models.Decimal, muUID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.put(uid, iuid, quantity, muUID, true)
+}
+
+// put() - общая часть Put() и Add(), при add количество прибавляется к строке корзины, вызывается под c.mu
+func (c *CartModel) put(uid, iuid string, quantity models.Decimal, muUID string, add bool) error {
+	var (
+		item *models.ItemOutput
+		unit *models.MeasureUnitOutput
+	)
+
+	for _, v := range itemList {
+		if v.ItemUID == iuid {
+			item = v
+		}
+	}
+	for _, v := range unitList {
+		if v.UUID == muUID {
+			unit = v
+		}
+	}
+	if item == nil || unit == nil {
+		return models.ErrBadReference
+	}
+
+	if c.carts == nil {
+		c.carts = make(map[string][]*models.CartLineOutput)
+	}
+
+	line := &models.CartLineOutput{
+		ItemUID:     item.ItemUID,
+		ShopUID:     item.ShopUID,
+		Name:        item.Name,
+		Vendor:      item.Vendor,
+		Price:       item.Price,
+		Quantity:    quantity,
+		MeasureUnit: unit.Unit,
+		InStock:     item.InStock,
+		Deleted:     !item.DeletedAt.IsZero() || shopDeleted(item.ShopUID),
+	}
+
+	for i, l := range c.carts[uid] {
+		if l.ItemUID == iuid {
+			if add {
+				sum, err := l.Quantity.Add(quantity)
+				if err != nil || sum > models.MaxQuantity {
+					return models.ErrDecimalOverflow
+				}
+				line.Quantity = sum
+			}
+			c.carts[uid][i] = line
+			return nil
+		}
+	}
+	c.carts[uid] = append(c.carts[uid], line)
+
+	return nil
+}
+
+func (c *CartModel) Remove(ctx context.Context, uid, iuid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, l := range c.carts[uid] {
+		if l.ItemUID == iuid {
+			c.carts[uid] = append(c.carts[uid][:i], c.carts[uid][i+1:]...)
+			return nil
+		}
+	}
+
+	return models.ErrNotInCart
+}
+
+func (c *CartModel) Clear(ctx context.Context, uid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.carts, uid)
+	return nil
+}
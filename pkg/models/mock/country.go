@@ -1,7 +1,7 @@
 package mock
 
 import (
-	"errors"
+	"context"
 
 	"github.com/JohanVong/online_bazaar/pkg/models"
 )
@@ -23,16 +23,20 @@ var countryList = []*models.CountryOutput{
 	},
 }
 
-func (c *CountryModel) GetList() ([]*models.CountryOutput, error) {
+func (c *CountryModel) GetList(ctx context.Context) ([]*models.CountryOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	return countryList, nil
 }
 
-func (c *CountryModel) GetByName(name string) (string, error) {
+func (c *CountryModel) GetByName(ctx context.Context, name string) (string, error) {
 	for _, v := range countryList {
 		if name == v.Name {
 			return v.UUID, nil
 		}
 	}
 
-	return "", errors.New("Provided country does not exist")
+	return "", models.ErrUnknownCountry
 }
@@ -0,0 +1,28 @@
+package mock
+
+import (
+	"context"
+	"errors"
+)
+
+// HealthModel - БД для проверок готовности: Down - БД недоступна, Version - версия схемы
+type HealthModel struct {
+	Down    bool
+	Version int
+}
+
+func (h *HealthModel) Ping(ctx context.Context) error {
+	if h.Down {
+		return errors.New("connection refused")
+	}
+
+	return ctx.Err()
+}
+
+func (h *HealthModel) SchemaVersion(ctx context.Context) (int, error) {
+	if h.Down {
+		return 0, errors.New("connection refused")
+	}
+
+	return h.Version, ctx.Err()
+}
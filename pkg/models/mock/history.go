@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// checkVersion() - проверка If-Match, как в db.touchHistory(), для записи с такими временами в истории
+func checkVersion(ctx context.Context, createdAt, updatedAt time.Time) error {
+	versions, ok := models.ExpectedVersions(ctx)
+	if !ok {
+		return nil
+	}
+
+	current := models.Version(createdAt, updatedAt)
+	for _, v := range versions {
+		if models.Version(v, time.Time{}) == current {
+			return nil
+		}
+	}
+
+	return models.ErrVersionMismatch
+}
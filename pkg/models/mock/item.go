@@ -0,0 +1,168 @@
+package mock
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+type ItemModel struct{}
+
+var itemList = []*models.ItemOutput{
+	{
+		ItemUID:     "item[1]",
+		ShopUID:     "shop[1]",
+		OwnerUID:    "uuid.v6[1]",
+		Name:        "TestItem",
+		Vendor:      "TestVendor",
+		Price:       1999,
+		Description: "Test item description",
+		InStock:     1000,
+		MeasureUnit: "pcs",
+		HistoryUID:  "uuid.v6[20]",
+		CreatedAt:   time.Now(),
+	},
+	{
+		ItemUID:     "item[2]",
+		ShopUID:     "shop[2]",
+		OwnerUID:    "uuid.v6[9]",
+		Name:        "ForeignItem",
+		Vendor:      "TestVendor",
+		Price:       50000,
+		Description: "Item of another seller",
+		InStock:     500,
+		MeasureUnit: "kg",
+		HistoryUID:  "uuid.v6[21]",
+		CreatedAt:   time.Now(),
+	},
+	{
+		ItemUID:     "item[3]",
+		ShopUID:     "shop[1]",
+		OwnerUID:    "uuid.v6[1]",
+		Name:        "DeletedItem",
+		Vendor:      "TestVendor",
+		Price:       100,
+		Description: "Item that was deleted",
+		InStock:     0,
+		MeasureUnit: "pcs",
+		HistoryUID:  "uuid.v6[22]",
+		CreatedAt:   time.Now(),
+		DeletedAt:   time.Now(),
+	},
+	{
+		ItemUID:     "item[5]",
+		ShopUID:     "shop[3]",
+		OwnerUID:    "uuid.v6[1]",
+		Name:        "ItemOfDeletedShop",
+		Vendor:      "TestVendor",
+		Price:       100,
+		Description: "Item of the shop that was deleted",
+		InStock:     1000,
+		MeasureUnit: "pcs",
+		HistoryUID:  "uuid.v6[23]",
+		CreatedAt:   time.Now(),
+	},
+}
+
+// touchItems() - списание остатка по заказу меняет версию его товаров, как в постгрес
+func touchItems(oo *models.OrderOutput) {
+	for _, l := range oo.Lines {
+		for _, v := range itemList {
+			if v.ItemUID == l.ItemUID {
+				v.UpdatedAt = time.Now()
+			}
+		}
+	}
+}
+
+func (i *ItemModel) Insert(ctx context.Context, input *models.ItemInput) (string, error) {
+	return "item[4]", nil
+}
+
+func (i *ItemModel) Get(ctx context.Context, iuid string) (*models.ItemOutput, error) {
+	for _, v := range itemList {
+		if v.ItemUID == iuid {
+			return v, nil
+		}
+	}
+
+	return nil, models.ErrItemNotFound
+}
+
+func (i *ItemModel) GetList(ctx context.Context, suid string) ([]*models.ItemOutput, error) {
+	var items []*models.ItemOutput
+
+	for _, v := range itemList {
+		if v.ShopUID == suid && v.DeletedAt.IsZero() && !shopDeleted(suid) {
+			items = append(items, v)
+		}
+	}
+
+	return items, nil
+}
+
+func (i *ItemModel) Search(ctx context.Context, input *models.ItemSearchInput) (*models.ItemSearchOutput, error) {
+	var out models.ItemSearchOutput
+
+	if input.Cursor == "bad" {
+		return nil, models.ErrBadCursor
+	}
+
+	for _, v := range itemList {
+		if !v.DeletedAt.IsZero() || shopDeleted(v.ShopUID) {
+			continue
+		}
+		if input.Query != "" && !strings.Contains(strings.ToLower(v.Name), strings.ToLower(input.Query)) {
+			continue
+		}
+		if (input.PriceMin > 0 && v.Price < input.PriceMin) || (input.PriceMax > 0 && v.Price > input.PriceMax) {
+			continue
+		}
+		if input.ShopUID != "" && v.ShopUID != input.ShopUID {
+			continue
+		}
+		if input.InStock && v.InStock <= 0 {
+			continue
+		}
+		out.Items = append(out.Items, v)
+	}
+
+	return &out, nil
+}
+
+func (i *ItemModel) Update(ctx context.Context, iuid string, input *models.ItemUpdateInput) error {
+	if input.Name == "" && input.Vendor == "" && input.Price == 0 && input.Description == "" {
+		return models.ErrNothingToUpdate
+	}
+
+	io, err := i.Get(ctx, iuid)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(ctx, io.CreatedAt, io.UpdatedAt)
+}
+
+func (i *ItemModel) Restock(ctx context.Context, iuid string, delta models.Decimal) error {
+	io, err := i.Get(ctx, iuid)
+	if err != nil {
+		return err
+	}
+
+	if io.InStock+delta < 0 {
+		return models.ErrNegativeStock
+	}
+
+	return checkVersion(ctx, io.CreatedAt, io.UpdatedAt)
+}
+
+func (i *ItemModel) Delete(ctx context.Context, iuid string) error {
+	io, err := i.Get(ctx, iuid)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(ctx, io.CreatedAt, io.UpdatedAt)
+}
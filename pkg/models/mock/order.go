@@ -0,0 +1,130 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+type OrderModel struct{}
+
+var orderList = []*models.OrderOutput{
+	{
+		OrderUID:   "order[1]",
+		UserUID:    "uuid.v6[1]",
+		Status:     models.StatusCreated,
+		HistoryUID: "uuid.v6[30]",
+		CreatedAt:  time.Now(),
+		Lines: []*models.OrderLineOutput{
+			{
+				ItemUID:     "item[2]",
+				ShopUID:     "shop[2]",
+				OwnerUID:    "uuid.v6[9]",
+				Name:        "ForeignItem",
+				Quantity:    100,
+				MeasureUnit: "pcs",
+				Price:       50000,
+				LineTotal:   50000,
+			},
+		},
+		Total: 50000,
+	},
+	{
+		OrderUID:   "order[2]",
+		UserUID:    "uuid.v6[9]",
+		Status:     models.StatusShipped,
+		HistoryUID: "uuid.v6[31]",
+		CreatedAt:  time.Now(),
+		Lines: []*models.OrderLineOutput{
+			{
+				ItemUID:     "item[1]",
+				ShopUID:     "shop[1]",
+				OwnerUID:    "uuid.v6[1]",
+				Name:        "TestItem",
+				Quantity:    200,
+				MeasureUnit: "pcs",
+				Price:       1999,
+				LineTotal:   3998,
+			},
+		},
+		Total: 3998,
+	},
+	{
+		OrderUID:   "order[3]",
+		UserUID:    "uuid.v6[4]",
+		Status:     models.StatusPaid,
+		HistoryUID: "uuid.v6[32]",
+		CreatedAt:  time.Now(),
+		Lines: []*models.OrderLineOutput{
+			{
+				ItemUID:     "item[1]",
+				ShopUID:     "shop[1]",
+				OwnerUID:    "uuid.v6[1]",
+				Name:        "TestItem",
+				Quantity:    100,
+				MeasureUnit: "pcs",
+				Price:       1999,
+				LineTotal:   1999,
+			},
+			{
+				ItemUID:     "item[2]",
+				ShopUID:     "shop[2]",
+				OwnerUID:    "uuid.v6[9]",
+				Name:        "ForeignItem",
+				Quantity:    100,
+				MeasureUnit: "kg",
+				Price:       50000,
+				LineTotal:   50000,
+			},
+		},
+		Total: 51999,
+	},
+}
+
+func (o *OrderModel) Checkout(ctx context.Context, uid string) (string, error) {
+	switch uid {
+	case "uuid.v6[1]":
+		touchItems(orderList[0])
+		return "order[1]", nil
+	case "uuid.v6[6]":
+		return "", models.ErrEmptyCart
+	default:
+		return "", models.ErrOutOfStock.Withf("%s", "ForeignItem")
+	}
+}
+
+func (o *OrderModel) Get(ctx context.Context, ouid string) (*models.OrderOutput, error) {
+	for _, v := range orderList {
+		if v.OrderUID == ouid {
+			return v, nil
+		}
+	}
+
+	return nil, models.ErrOrderNotFound
+}
+
+func (o *OrderModel) GetList(ctx context.Context, uid string) ([]*models.OrderOutput, error) {
+	var orders []*models.OrderOutput
+
+	for _, v := range orderList {
+		if v.UserUID == uid {
+			orders = append(orders, v)
+		}
+	}
+
+	return orders, nil
+}
+
+func (o *OrderModel) Transition(ctx context.Context, ouid, to, actorUID string) error {
+	oo, err := o.Get(ctx, ouid)
+	if err != nil {
+		return err
+	}
+
+	if !models.CanTransition(oo.Status, to) {
+		return models.ErrBadTransition.Withf("from %s to %s", oo.Status, to)
+	}
+
+	return checkVersion(ctx, oo.CreatedAt, oo.UpdatedAt)
+}
@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationModel - хранилище отозванных токенов в памяти
+type RevocationModel struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]time.Time
+}
+
+func (r *RevocationModel) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.tokens == nil {
+		r.tokens = make(map[string]time.Time)
+	}
+
+	r.purge(time.Now())
+	r.tokens[jti] = expiresAt
+
+	return nil
+}
+
+func (r *RevocationModel) RevokeAll(ctx context.Context, uid string, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.users == nil {
+		r.users = make(map[string]time.Time)
+	}
+	r.users[uid] = before
+
+	return nil
+}
+
+func (r *RevocationModel) IsRevoked(ctx context.Context, jti, uid string, issuedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expiresAt, ok := r.tokens[jti]; ok && !expiresAt.Before(time.Now()) {
+		return true, nil
+	}
+
+	before, ok := r.users[uid]
+	if ok && !before.Before(issuedAt) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func (r *RevocationModel) Purge(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.purge(before), nil
+}
+
+// purge() - удаляет токены, истекшие до before, вызывается под r.mu
+func (r *RevocationModel) purge(before time.Time) int64 {
+	var n int64
+	for jti, exp := range r.tokens {
+		if exp.Before(before) {
+			delete(r.tokens, jti)
+			n++
+		}
+	}
+
+	return n
+}
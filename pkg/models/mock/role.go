@@ -0,0 +1,25 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+type RoleModel struct{}
+
+func (r *RoleModel) Grant(ctx context.Context, uid string, role string) error {
+	if uid != "uuid.v6[1]" && uid != "uuid.v6[6]" {
+		return models.ErrBadReference
+	}
+
+	return nil
+}
+
+func (r *RoleModel) Revoke(ctx context.Context, uid string, role string) error {
+	if uid != "uuid.v6[1]" && uid != "uuid.v6[6]" {
+		return models.ErrBadReference
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+type ShopModel struct{}
+
+var shopList = []*models.ShopOutput{
+	{
+		ShopUID:     "shop[1]",
+		Name:        "TestShop",
+		Description: "Test shop description",
+		OwnerUID:    "uuid.v6[1]",
+		HistoryUID:  "uuid.v6[10]",
+		CreatedAt:   time.Now(),
+	},
+	{
+		ShopUID:     "shop[2]",
+		Name:        "ForeignShop",
+		Description: "Shop of another user",
+		OwnerUID:    "uuid.v6[9]",
+		HistoryUID:  "uuid.v6[11]",
+		CreatedAt:   time.Now(),
+	},
+	{
+		ShopUID:     "shop[3]",
+		Name:        "DeletedShop",
+		Description: "Shop that was deleted",
+		OwnerUID:    "uuid.v6[1]",
+		HistoryUID:  "uuid.v6[12]",
+		CreatedAt:   time.Now(),
+		DeletedAt:   time.Now(),
+	},
+}
+
+// shopDeleted() - проверяет, что магазин из фикстур удален
+func shopDeleted(suid string) bool {
+	for _, v := range shopList {
+		if v.ShopUID == suid {
+			return !v.DeletedAt.IsZero()
+		}
+	}
+
+	return false
+}
+
+func (s *ShopModel) Insert(ctx context.Context, uid string, input *models.ShopInput) (string, error) {
+	if input.Name == "Exists" {
+		return "", models.ErrShopExists
+	}
+
+	return "shop[4]", nil
+}
+
+func (s *ShopModel) Get(ctx context.Context, suid string) (*models.ShopOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range shopList {
+		if v.ShopUID == suid {
+			return v, nil
+		}
+	}
+
+	return nil, models.ErrShopNotFound
+}
+
+func (s *ShopModel) GetList(ctx context.Context, ownerUID string) ([]*models.ShopOutput, error) {
+	var shops []*models.ShopOutput
+
+	for _, v := range shopList {
+		if v.DeletedAt.IsZero() && (ownerUID == "" || v.OwnerUID == ownerUID) {
+			shops = append(shops, v)
+		}
+	}
+
+	return shops, nil
+}
+
+func (s *ShopModel) Update(ctx context.Context, suid string, input *models.ShopUpdateInput) error {
+	if input.Name == "" && input.Description == "" {
+		return models.ErrNothingToUpdate
+	}
+
+	so, err := s.Get(ctx, suid)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(ctx, so.CreatedAt, so.UpdatedAt)
+}
+
+func (s *ShopModel) Delete(ctx context.Context, suid string) error {
+	so, err := s.Get(ctx, suid)
+	if err != nil {
+		return err
+	}
+
+	return checkVersion(ctx, so.CreatedAt, so.UpdatedAt)
+}
@@ -0,0 +1,95 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+// TokenModel - хранит refresh-токены в памяти, чтобы можно было проверить ротацию
+type TokenModel struct {
+	mu      sync.Mutex
+	counter int
+	tokens  map[string]*models.RefreshTokenOutput
+}
+
+func (t *TokenModel) Insert(ctx context.Context, userUID, familyUID, hash string, expiresAt time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tokens == nil {
+		t.tokens = make(map[string]*models.RefreshTokenOutput)
+	}
+
+	t.counter++
+	if familyUID == "" {
+		familyUID = fmt.Sprintf("family[%v]", t.counter)
+	}
+
+	t.tokens[hash] = &models.RefreshTokenOutput{
+		TokenUID:  fmt.Sprintf("token[%v]", t.counter),
+		FamilyUID: familyUID,
+		UserUID:   userUID,
+		ExpiresAt: expiresAt,
+	}
+
+	return nil
+}
+
+func (t *TokenModel) Get(ctx context.Context, hash string) (*models.RefreshTokenOutput, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rt, ok := t.tokens[hash]
+	if !ok {
+		return nil, models.ErrBadRefreshToken
+	}
+
+	out := *rt
+	return &out, nil
+}
+
+func (t *TokenModel) Rotate(ctx context.Context, old *models.RefreshTokenOutput, hash string, expiresAt time.Time) error {
+	t.mu.Lock()
+	for _, rt := range t.tokens {
+		if rt.TokenUID == old.TokenUID {
+			if !rt.UsedAt.IsZero() || !rt.RevokedAt.IsZero() {
+				t.mu.Unlock()
+				return models.ErrTokenReused
+			}
+			rt.UsedAt = time.Now()
+		}
+	}
+	t.mu.Unlock()
+
+	return t.Insert(ctx, old.UserUID, old.FamilyUID, hash, expiresAt)
+}
+
+func (t *TokenModel) RevokeFamily(ctx context.Context, familyUID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rt := range t.tokens {
+		if rt.FamilyUID == familyUID && rt.RevokedAt.IsZero() {
+			rt.RevokedAt = time.Now()
+		}
+	}
+
+	return nil
+}
+
+func (t *TokenModel) RevokeUser(ctx context.Context, userUID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, rt := range t.tokens {
+		if rt.UserUID == userUID && rt.RevokedAt.IsZero() {
+			rt.RevokedAt = time.Now()
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/JohanVong/online_bazaar/pkg/models"
+)
+
+type UnitModel struct{}
+
+var unitList = []*models.MeasureUnitOutput{
+	{
+		UUID:       "unit[1]",
+		Unit:       "kg",
+		Fractional: true,
+	},
+	{
+		UUID:       "unit[2]",
+		Unit:       "pcs",
+		Fractional: false,
+	},
+}
+
+func (u *UnitModel) GetList(ctx context.Context) ([]*models.MeasureUnitOutput, error) {
+	return unitList, nil
+}
+
+func (u *UnitModel) GetByName(ctx context.Context, name string) (*models.MeasureUnitOutput, error) {
+	for _, v := range unitList {
+		if name == v.Unit {
+			return v, nil
+		}
+	}
+
+	return nil, models.ErrUnknownUnit
+}
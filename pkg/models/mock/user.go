@@ -1,13 +1,16 @@
 package mock
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"github.com/JohanVong/online_bazaar/pkg/models"
 )
 
-type UserModel struct{}
+type UserModel struct {
+	// Rehashed - хэши, сохраненные через UpdateHash(), по ключу пользователя, если карта задана
+	Rehashed map[string]string
+}
 
 var mockUser = &models.UserOutput{
 	UserUID:    "uuid.v6[1]",
@@ -21,6 +24,22 @@ var mockUser = &models.UserOutput{
 	CreatedAt:  time.Now(),
 	UpdatedAt:  *new(time.Time),
 	DeletedAt:  *new(time.Time),
+	Roles:      []string{"buyer"},
+}
+
+var mockAdmin = &models.UserOutput{
+	UserUID:    "uuid.v6[6]",
+	Username:   "TestAdmin",
+	Hash:       "hzNDoZShWoQPmw9HmK1RvVeE8PtMJpDHR4ru5+QVnwL0NdqVBUmb7x7rUDahYMBSfTS3zzJg7WE7DIJBexaWWQ==",
+	Email:      "testadmin@mail.test",
+	Phone:      "87770001122",
+	CountryUID: "uuid.v6[2]",
+	Country:    "TestCountry",
+	HistoryUID: "uuid.v6[7]",
+	CreatedAt:  time.Now(),
+	UpdatedAt:  *new(time.Time),
+	DeletedAt:  *new(time.Time),
+	Roles:      []string{"admin", "buyer"},
 }
 
 var mockUserDeleted = &models.UserOutput{
@@ -35,21 +54,22 @@ var mockUserDeleted = &models.UserOutput{
 	CreatedAt:  time.Now(),
 	UpdatedAt:  *new(time.Time),
 	DeletedAt:  time.Now(),
+	Roles:      []string{"buyer"},
 }
 
-func (u *UserModel) Insert(input *models.UserSignupInput) error {
+func (u *UserModel) Insert(ctx context.Context, input *models.UserSignupInput) error {
 	if input.Username == "Exists" {
-		return errors.New("duplicate key value violates unique constraint")
+		return models.ErrUserExists
 	}
 
 	if input.Country != "TestCountry" {
-		return errors.New("Provided country does not exist")
+		return models.ErrUnknownCountry
 	}
 
 	return nil
 }
 
-func (u *UserModel) Get(key string, byPK bool) (*models.UserOutput, error) {
+func (u *UserModel) Get(ctx context.Context, key string, byPK bool) (*models.UserOutput, error) {
 	switch {
 	case key == "uuid.v6[1]" && byPK:
 		time.Sleep(time.Millisecond * 100)
@@ -67,48 +87,72 @@ func (u *UserModel) Get(key string, byPK bool) (*models.UserOutput, error) {
 		time.Sleep(time.Millisecond * 200)
 		return mockUserDeleted, nil
 
+	case key == "uuid.v6[6]" && byPK:
+		time.Sleep(time.Millisecond * 100)
+		return mockAdmin, nil
+
+	case key == "TestAdmin" && !byPK:
+		time.Sleep(time.Millisecond * 200)
+		return mockAdmin, nil
+
 	case key == "panic":
 		panic("test panic!")
 
 	default:
-		return nil, errors.New("No record found")
+		return nil, models.ErrUserNotFound
 	}
 }
 
-func (u *UserModel) Update(uid string, input *models.UserUpdateInput) error {
-	counter := 0
+func (u *UserModel) Update(ctx context.Context, uid string, input *models.UserUpdateInput) error {
+	if len(input.Present()) == 0 {
+		return models.ErrNothingToUpdate
+	}
 
-	if input.Email != "" {
-		counter++
+	if input.Email.String == "taken@mail.test" {
+		return models.ErrUserExists
 	}
 
-	if input.Phone != "" {
-		counter++
+	return u.checkVersion(ctx, uid)
+}
+
+func (u *UserModel) UpdatePassword(ctx context.Context, uid string, input *models.UpdateUserPasswordInput) error {
+	if uid != "uuid.v6[1]" {
+		return models.ErrUserNotFound
 	}
 
-	if input.Country != "" {
-		counter++
+	return u.checkVersion(ctx, uid)
+}
+
+// checkVersion() - проверка If-Match; пользователь достается, только если она нужна, потому что Get() медленный
+func (u *UserModel) checkVersion(ctx context.Context, uid string) error {
+	if _, ok := models.ExpectedVersions(ctx); !ok {
+		return nil
 	}
 
-	if counter <= 0 {
-		return errors.New("Nothing to update")
+	uo, err := u.Get(ctx, uid, true)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return checkVersion(ctx, uo.CreatedAt, uo.UpdatedAt)
 }
 
-func (u *UserModel) UpdatePassword(uid string, input *models.UpdateUserPasswordInput) error {
-	if uid != "uuid.v6[1]" {
-		return errors.New("No record found")
+func (u *UserModel) UpdateHash(ctx context.Context, uid string, hash string) error {
+	if uid != "uuid.v6[1]" && uid != "uuid.v6[4]" && uid != "uuid.v6[6]" {
+		return models.ErrUserNotFound
+	}
+
+	if u.Rehashed != nil {
+		u.Rehashed[uid] = hash
 	}
 
 	return nil
 }
 
-func (u *UserModel) Delete(uid string) error {
+func (u *UserModel) Delete(ctx context.Context, uid string) error {
 	if uid != "uuid.v6[1]" {
-		return errors.New("No record found")
+		return models.ErrUserNotFound
 	}
 
-	return nil
+	return u.checkVersion(ctx, uid)
 }
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+)
+
+/*
+NullString - строковое поле частичного обновления по RFC 7396 (JSON Merge Patch).
+Различает три случая: поля нет в запросе (Set == false) - значение не трогается,
+null (Null == true) - значение очищается, строка - значение заменяется.
+*/
+type NullString struct {
+	String string
+	Set    bool
+	Null   bool
+}
+
+// UnmarshalJSON() - вызывается только для полей, которые есть в запросе, в том числе для null
+func (ns *NullString) UnmarshalJSON(data []byte) error {
+	ns.Set = true
+	ns.Null = bytes.Equal(data, []byte("null"))
+	if ns.Null {
+		ns.String = ""
+		return nil
+	}
+
+	return json.Unmarshal(data, &ns.String)
+}
+
+// MarshalJSON() - отсутствующее поле и null пишутся как null
+func (ns NullString) MarshalJSON() ([]byte, error) {
+	if !ns.Set || ns.Null {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(ns.String)
+}
+
+// Value() - значение для запроса к БД, null превращается в NULL
+func (ns NullString) Value() (driver.Value, error) {
+	if ns.Null {
+		return nil, nil
+	}
+
+	return ns.String, nil
+}
+
+// NullStringValue() - функция для validator.RegisterCustomTypeFunc: правила проверяют саму строку, null - как пустую
+func NullStringValue(v reflect.Value) interface{} {
+	if ns, ok := v.Interface().(NullString); ok {
+		return ns.String
+	}
+
+	return nil
+}
+
+// present() - имена полей NullString структуры s, которые пришли в запросе
+func present(s interface{}) []string {
+	v := reflect.Indirect(reflect.ValueOf(s))
+
+	var fields []string
+	for i := 0; i < v.NumField(); i++ {
+		if ns, ok := v.Field(i).Interface().(NullString); ok && ns.Set {
+			fields = append(fields, v.Type().Field(i).Name)
+		}
+	}
+
+	return fields
+}
+
+// omitEmpty() - считает пустые строки в полях NullString структуры s отсутствующими
+func omitEmpty(s interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(s))
+
+	for i := 0; i < v.NumField(); i++ {
+		if ns, ok := v.Field(i).Interface().(NullString); ok && !ns.Null && ns.String == "" {
+			v.Field(i).Set(reflect.ValueOf(NullString{}))
+		}
+	}
+}
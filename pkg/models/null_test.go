@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullStringJSON(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantPresent []string
+		wantPhone   NullString
+	}{
+		{`{}`, nil, NullString{}},
+		{`{"Phone": null}`, []string{"Phone"}, NullString{Set: true, Null: true}},
+		{`{"Phone": ""}`, []string{"Phone"}, NullString{Set: true}},
+		{`{"Phone": "+7", "Email": "a@b.cc"}`, []string{"Email", "Phone"}, NullString{String: "+7", Set: true}},
+	}
+
+	for _, tt := range tests {
+		var uu UserUpdateInput
+		if assert.NoError(t, json.Unmarshal([]byte(tt.input), &uu), tt.input) {
+			assert.Equal(t, tt.wantPresent, uu.Present(), tt.input)
+			assert.Equal(t, tt.wantPhone, uu.Phone, tt.input)
+		}
+	}
+
+	var uu UserUpdateInput
+	assert.Error(t, json.Unmarshal([]byte(`{"Phone": 7}`), &uu))
+
+	b, err := json.Marshal(UserUpdateInput{Email: NullString{String: "a@b.cc", Set: true}, Phone: NullString{Set: true, Null: true}})
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"Email": "a@b.cc", "Phone": null, "Country": null}`, string(b))
+	}
+}
+
+func TestNullStringValue(t *testing.T) {
+	v, err := NullString{Set: true, Null: true}.Value()
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = NullString{String: "+7", Set: true}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "+7", v)
+}
+
+func TestOmitEmpty(t *testing.T) {
+	uu := UserUpdateInput{
+		Email:   NullString{Set: true},
+		Phone:   NullString{Set: true, Null: true},
+		Country: NullString{String: "Kazakhstan", Set: true},
+	}
+	uu.OmitEmpty()
+
+	assert.Equal(t, []string{"Phone", "Country"}, uu.Present())
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+var (
+	// ErrEmptyCart - оформлять нечего
+	ErrEmptyCart = newError(KindInvalid, "cart_empty", "Cart is empty")
+	// ErrOutOfStock - товара не хватает на складе или он удален
+	ErrOutOfStock = newError(KindConflict, "out_of_stock", "Not enough items in stock")
+	// ErrBadTransition - переход между статусами заказа не разрешен
+	ErrBadTransition = newError(KindConflict, "bad_transition", "Order status transition is not allowed")
+	// ErrOrderNotFound - заказа нет
+	ErrOrderNotFound = newError(KindNotFound, "order_not_found", "Order not found")
+)
+
+// Статусы заказов
+const (
+	StatusCreated   = "created"
+	StatusPaid      = "paid"
+	StatusShipped   = "shipped"
+	StatusDelivered = "delivered"
+	StatusCancelled = "cancelled"
+	StatusRefunded  = "refunded"
+)
+
+/*
+orderTransitions - жизненный цикл заказа: из какого статуса в какие можно перейти.
+Это единственное место, где описаны разрешенные переходы, модели и хэндлеры
+проверяют их только через CanTransition().
+*/
+var orderTransitions = map[string][]string{
+	StatusCreated:   {StatusPaid, StatusCancelled},
+	StatusPaid:      {StatusShipped, StatusCancelled, StatusRefunded},
+	StatusShipped:   {StatusDelivered},
+	StatusDelivered: {StatusRefunded},
+}
+
+// CanTransition() - проверяет, можно ли перевести заказ из статуса from в статус to
+func CanTransition(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OrderStatusInput - структура запроса в апи для смены статуса заказа продавцом
+type OrderStatusInput struct {
+	Status string `json:"Status" validate:"required,oneof=paid shipped delivered cancelled refunded"`
+}
+
+// OrderLineOutput - строка заказа с ценой на момент оформления
+type OrderLineOutput struct {
+	ItemUID     string
+	ShopUID     string
+	OwnerUID    string `json:"-"`
+	Name        string
+	Quantity    Decimal
+	MeasureUnit string
+	Price       Decimal
+	LineTotal   Decimal
+}
+
+// OrderTransitionOutput - запись о смене статуса заказа
+type OrderTransitionOutput struct {
+	From      string
+	To        string
+	ActorUID  string
+	CreatedAt time.Time
+}
+
+// OrderOutput - вью апи для заказа
+type OrderOutput struct {
+	OrderUID    string
+	UserUID     string
+	Status      string
+	HistoryUID  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Lines       []*OrderLineOutput       `json:",omitempty"`
+	Total       Decimal                  `json:",omitempty"`
+	Transitions []*OrderTransitionOutput `json:",omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{StatusCreated, StatusPaid, true},
+		{StatusCreated, StatusCancelled, true},
+		{StatusCreated, StatusShipped, false},
+		{StatusPaid, StatusShipped, true},
+		{StatusPaid, StatusRefunded, true},
+		{StatusShipped, StatusDelivered, true},
+		{StatusShipped, StatusCancelled, false},
+		{StatusDelivered, StatusRefunded, true},
+		{StatusCancelled, StatusPaid, false},
+		{StatusRefunded, StatusCreated, false},
+		{"unknown", StatusPaid, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CanTransition(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+var (
+	// ErrUnknownRole - роли нет в справочнике
+	ErrUnknownRole = newError(KindInvalid, "unknown_role", "Provided role does not exist")
+	// ErrOwnAdminRole - администратор пытается снять роль с себя
+	ErrOwnAdminRole = newError(KindForbidden, "own_admin_role", "Admin can not revoke own admin role")
+)
+
+// Роли пользователей
+const (
+	RoleBuyer  = "buyer"
+	RoleSeller = "seller"
+	RoleAdmin  = "admin"
+)
+
+// RoleInput - структура запроса в апи для выдачи или отзыва роли
+type RoleInput struct {
+	UserUID string `json:"UserUID" validate:"required"`
+	Role    string `json:"Role" validate:"required,oneof=buyer seller admin"`
+}
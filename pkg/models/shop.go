@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+var (
+	// ErrShopNotFound - магазина нет
+	ErrShopNotFound = newError(KindNotFound, "shop_not_found", "Shop not found")
+	// ErrShopDeleted - магазин удален
+	ErrShopDeleted = newError(KindNotFound, "shop_deleted", "Shop was deleted")
+	// ErrShopExists - название магазина занято
+	ErrShopExists = newError(KindConflict, "shop_exists", "Shop name is already taken")
+)
+
+// ShopInput - структура запроса в апи для создания магазина
+type ShopInput struct {
+	Name        string `json:"Name" validate:"required,max=60"`
+	Description string `json:"Description"`
+}
+
+// ShopUpdateInput - структура запроса в апи для обновления некоторых данных магазина
+type ShopUpdateInput struct {
+	Name        string `json:"Name" validate:"max=60"`
+	Description string `json:"Description"`
+}
+
+// ShopOutput - вью апи для получения данных о магазине
+type ShopOutput struct {
+	ShopUID     string
+	Name        string
+	Description string
+	OwnerUID    string
+	HistoryUID  string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   time.Time
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+var (
+	// ErrTokenReused - refresh-токен уже был обменян или отозван
+	ErrTokenReused = newError(KindUnauthorized, "token_reused", "Refresh token reuse detected")
+	// ErrBadRefreshToken - refresh-токен неизвестен или отозван
+	ErrBadRefreshToken = newError(KindUnauthorized, "invalid_refresh_token", "Invalid refresh token")
+	// ErrRefreshTokenExpired - срок refresh-токена истек
+	ErrRefreshTokenExpired = newError(KindUnauthorized, "refresh_token_expired", "Refresh token expired")
+	// ErrBadToken - токен доступа не прошел проверку
+	ErrBadToken = newError(KindUnauthorized, "invalid_token", "Invalid token")
+	// ErrTokenRevoked - токен доступа отозван
+	ErrTokenRevoked = newError(KindUnauthorized, "token_revoked", "Token was revoked")
+)
+
+// RefreshTokenInput - структура запроса в апи для обновления токена
+type RefreshTokenInput struct {
+	RefreshToken string `json:"RefreshToken" validate:"required"`
+}
+
+// LogoutInput - структура запроса в апи для выхода.
+// Если передан refresh-токен, его цепочка тоже отзывается
+type LogoutInput struct {
+	RefreshToken string `json:"RefreshToken"`
+}
+
+// RefreshTokenOutput - данные о refresh-токене из БД.
+// Сам токен не хранится, только его хэш
+type RefreshTokenOutput struct {
+	TokenUID  string
+	FamilyUID string
+	UserUID   string
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	RevokedAt time.Time
+}
@@ -0,0 +1,11 @@
+package models
+
+// ErrUnknownUnit - единицы измерения нет в справочнике
+var ErrUnknownUnit = newError(KindInvalid, "unknown_measure_unit", "Provided measure unit does not exist")
+
+// MeasureUnitOutput - структура на выход для единицы измерения
+type MeasureUnitOutput struct {
+	UUID       string `json:"-"`
+	Unit       string
+	Fractional bool
+}
@@ -2,6 +2,17 @@ package models
 
 import "time"
 
+var (
+	// ErrUserNotFound - пользователя нет
+	ErrUserNotFound = newError(KindNotFound, "user_not_found", "User not found")
+	// ErrUserDeleted - пользователь деактивирован
+	ErrUserDeleted = newError(KindUnauthorized, "user_deleted", "User was deleted")
+	// ErrUserExists - имя пользователя или почта заняты
+	ErrUserExists = newError(KindConflict, "user_exists", "Username or email is already taken")
+	// ErrBadCredentials - неверное имя пользователя или пароль
+	ErrBadCredentials = newError(KindUnauthorized, "wrong_credentials", "Wrong credentials provided")
+)
+
 // UserLoginInput - структура запроса в апи для логина
 type UserLoginInput struct {
 	Username string
@@ -10,7 +21,8 @@ type UserLoginInput struct {
 
 // UserLoginOutput - структура ответа апи для логина
 type UserLoginOutput struct {
-	Token string `json:"Token"`
+	Token        string `json:"Token"`
+	RefreshToken string `json:"RefreshToken"`
 }
 
 // UserSignupInput - структура запроса в апи для регистрации
@@ -22,11 +34,25 @@ type UserSignupInput struct {
 	Country  string `json:"Country"`
 }
 
-// UserUpdateInput - структура запроса в апи для обновления некоторых данных
+/*
+UserUpdateInput - структура запроса в апи для частичного обновления данных пользователя.
+Поля, которых нет в запросе, не меняются, null очищает поле (очистить можно только Phone).
+Правила validate проверяются только для пришедших полей, см. Present().
+*/
 type UserUpdateInput struct {
-	Email   string `json:"Email" validate:"email"`
-	Phone   string `json:"Phone"`
-	Country string `json:"Country"`
+	Email   NullString `json:"Email" validate:"required,email,max=60"`
+	Phone   NullString `json:"Phone" validate:"max=30"`
+	Country NullString `json:"Country" validate:"required"`
+}
+
+// Present() - поля, которые пришли в запросе
+func (uu *UserUpdateInput) Present() []string {
+	return present(uu)
+}
+
+// OmitEmpty() - для старого PUT /user/update, где пустая строка значит "не менять"
+func (uu *UserUpdateInput) OmitEmpty() {
+	omitEmpty(uu)
 }
 
 // UpdateUserPasswordInput - структура запроса в апи для обновления пароля
@@ -47,4 +73,36 @@ type UserOutput struct {
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt  time.Time
+	Roles      []string
+}
+
+// UserProfileOutput - вью апи профиля текущего пользователя, без хэша пароля и служебных ключей
+type UserProfileOutput struct {
+	UserUID   string
+	Username  string
+	Email     string
+	Phone     string
+	Country   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Roles     []string
+}
+
+// Profile() - профиль пользователя для него самого
+func (uo *UserOutput) Profile() *UserProfileOutput {
+	roles := uo.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+
+	return &UserProfileOutput{
+		UserUID:   uo.UserUID,
+		Username:  uo.Username,
+		Email:     uo.Email,
+		Phone:     uo.Phone,
+		Country:   uo.Country,
+		CreatedAt: uo.CreatedAt,
+		UpdatedAt: uo.UpdatedAt,
+		Roles:     roles,
+	}
 }
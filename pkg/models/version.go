@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// ErrVersionMismatch - запись изменилась после того, как клиент ее прочитал (If-Match не совпал)
+var ErrVersionMismatch = newError(KindPrecondition, "version_mismatch", "Record was changed since it was read")
+
+/*
+Version() - версия записи по ее строке в histories: время последнего изменения,
+а если изменений не было - время создания, в микросекундах (точность timestamp в постгрес).
+Клиенту она отдается как ETag, в If-Match возвращается обратно.
+*/
+func Version(createdAt, updatedAt time.Time) string {
+	t := updatedAt
+	if t.IsZero() {
+		t = createdAt
+	}
+
+	return strconv.FormatInt(t.UnixMicro(), 36)
+}
+
+// ParseVersion() - время изменения записи по ее версии
+func ParseVersion(v string) (time.Time, error) {
+	n, err := strconv.ParseInt(v, 36, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMicro(n).UTC(), nil
+}
+
+type versionsKey struct{}
+
+/*
+WithExpectedVersions() - контекст для условного изменения: модели меняют запись, только если
+ее версия совпадает с одной из versions, иначе отдают ErrVersionMismatch
+*/
+func WithExpectedVersions(ctx context.Context, versions []time.Time) context.Context {
+	return context.WithValue(ctx, versionsKey{}, versions)
+}
+
+// ExpectedVersions() - версии из WithExpectedVersions(), второе значение false, если изменение безусловное
+func ExpectedVersions(ctx context.Context) ([]time.Time, bool) {
+	versions, ok := ctx.Value(versionsKey{}).([]time.Time)
+	return versions, ok
+}
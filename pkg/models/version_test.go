@@ -0,0 +1,35 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion(t *testing.T) {
+	created := time.Date(2026, 10, 18, 12, 30, 0, 123456789, time.UTC)
+	updated := created.Add(time.Hour)
+
+	assert.Equal(t, Version(created, time.Time{}), Version(created.Truncate(time.Microsecond), time.Time{}), "precision is a microsecond")
+	assert.NotEqual(t, Version(created, time.Time{}), Version(created, updated), "updated_at wins over created_at")
+
+	v, err := ParseVersion(Version(created, updated))
+	if assert.NoError(t, err) {
+		assert.Equal(t, updated.Truncate(time.Microsecond), v)
+	}
+
+	_, err = ParseVersion("not a version!")
+	assert.Error(t, err)
+}
+
+func TestExpectedVersions(t *testing.T) {
+	_, ok := ExpectedVersions(context.Background())
+	assert.False(t, ok)
+
+	want := []time.Time{time.Now()}
+	got, ok := ExpectedVersions(WithExpectedVersions(context.Background(), want))
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ErrMalformedHash - ошибка разбора сохраненного хэша пароля
+var ErrMalformedHash = errors.New("Malformed password hash")
+
+// PasswordHasher - хэшер паролей на argon2id.
+// Параметры и соль кодируются в самом хэше, поэтому их можно менять,
+// не ломая проверку уже сохраненных паролей.
+type PasswordHasher struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// NewPasswordHasher() - создает хэшер с параметрами по умолчанию
+func NewPasswordHasher() *PasswordHasher {
+	return &PasswordHasher{
+		Memory:  64 * 1024,
+		Time:    1,
+		Threads: 4,
+		SaltLen: 16,
+		KeyLen:  32,
+	}
+}
+
+// Hash() - хэширует пароль со случайной солью и отдает хэш в формате
+// $argon2id$v=19$m=65536,t=1,p=4$<соль>$<ключ>
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+/*
+Verify() - сверяет пароль с сохраненным хэшем.
+Второе возвращаемое значение говорит о том, что хэш устарел
+(старый формат sha512 или другие параметры) и его стоит пересчитать.
+*/
+func (h *PasswordHasher) Verify(password, encoded string) (bool, bool, error) {
+	if !strings.HasPrefix(encoded, "$argon2id$") {
+		return verifyLegacy(password, encoded), true, nil
+	}
+
+	var (
+		version, memory, time uint32
+		threads               uint8
+	)
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	if version != argon2.Version {
+		return false, false, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	rehash := memory != h.Memory || time != h.Time || threads != h.Threads ||
+		uint32(len(salt)) != h.SaltLen || uint32(len(key)) != h.KeyLen
+
+	return true, rehash, nil
+}
+
+// verifyLegacy() - проверка пароля по старому формату (sha512 без соли в base64)
+func verifyLegacy(password, encoded string) bool {
+	hash64 := sha512.Sum512([]byte(password))
+	hash := base64.StdEncoding.EncodeToString(hash64[:])
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1
+}
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	h := &PasswordHasher{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+	hash, err := h.Hash("TestPassword")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	other, err := h.Hash("TestPassword")
+	if assert.NoError(t, err) {
+		assert.NotEqual(t, hash, other, "same password must get different salts")
+	}
+
+	tests := []struct {
+		password   string
+		encoded    string
+		hasher     *PasswordHasher
+		wantMatch  bool
+		wantRehash bool
+		wantErr    error
+	}{
+		{ // correct password
+			"TestPassword",
+			hash,
+			h,
+			true,
+			false,
+			nil,
+		},
+		{ // wrong password
+			"Test",
+			hash,
+			h,
+			false,
+			false,
+			nil,
+		},
+		{ // parameters changed since hashing
+			"TestPassword",
+			hash,
+			&PasswordHasher{Memory: 2048, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32},
+			true,
+			true,
+			nil,
+		},
+		{ // legacy sha512 hash
+			"TestPassword",
+			"hzNDoZShWoQPmw9HmK1RvVeE8PtMJpDHR4ru5+QVnwL0NdqVBUmb7x7rUDahYMBSfTS3zzJg7WE7DIJBexaWWQ==",
+			h,
+			true,
+			true,
+			nil,
+		},
+		{ // legacy sha512 hash, wrong password
+			"Test",
+			"hzNDoZShWoQPmw9HmK1RvVeE8PtMJpDHR4ru5+QVnwL0NdqVBUmb7x7rUDahYMBSfTS3zzJg7WE7DIJBexaWWQ==",
+			h,
+			false,
+			true,
+			nil,
+		},
+		{ // malformed hash
+			"TestPassword",
+			"$argon2id$v=19$broken",
+			h,
+			false,
+			false,
+			ErrMalformedHash,
+		},
+	}
+
+	for _, tt := range tests {
+		match, rehash, err := tt.hasher.Verify(tt.password, tt.encoded)
+		assert.Equal(t, tt.wantErr, err)
+		assert.Equal(t, tt.wantMatch, match)
+		if tt.wantMatch {
+			assert.Equal(t, tt.wantRehash, rehash)
+		}
+	}
+}
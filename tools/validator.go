@@ -1,17 +1,161 @@
 package tools
 
-import "github.com/go-playground/validator"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator"
+)
 
 // CustomValidator - кастомный валидатор для приложения
 type CustomValidator struct {
 	Validator *validator.Validate
 }
 
-// Validate - валидирует входящие данные в контроллере по тэгам `validate:""`
+/*
+NewCustomValidator() - создает валидатор, который называет поля так же, как клиент:
+по тэгу json, а для параметров строки запроса - по тэгу query
+*/
+func NewCustomValidator() *CustomValidator {
+	v := validator.New()
+	v.RegisterTagNameFunc(fieldName)
+
+	return &CustomValidator{Validator: v}
+}
+
+// partial - входящие данные частичного обновления, которые знают, какие поля пришли в запросе
+type partial interface {
+	Present() []string
+}
+
+/*
+Validate - валидирует входящие данные в контроллере по тэгам `validate:""`.
+У данных частичного обновления проверяются только пришедшие поля.
+*/
 func (cv *CustomValidator) Validate(i interface{}) error {
+	if p, ok := i.(partial); ok {
+		return cv.Validator.StructPartial(i, p.Present()...)
+	}
+
 	if err := cv.Validator.Struct(i); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// FieldError - описание поля, не прошедшего валидацию: имя поля, нарушенное правило и текст для человека
+type FieldError struct {
+	Field   string `json:"Field"`
+	Rule    string `json:"Rule"`
+	Message string `json:"Message"`
+}
+
+// rules - правила, для которых RegisterTranslations() подключает переводы
+var rules = []string{"required", "email", "oneof", "min", "max", "gte", "lte", "gt", "lt"}
+
+/*
+RegisterTranslations() - подключает переводы сообщений о правилах валидации.
+Сами тексты (ключи rule.*) переводчики уже должны содержать, здесь только выбирается ключ
+и подставляются имя поля и параметр правила.
+*/
+func (cv *CustomValidator) RegisterTranslations(translators ...ut.Translator) error {
+	for _, trans := range translators {
+		for _, rule := range rules {
+			err := cv.Validator.RegisterTranslation(rule, trans, func(ut.Translator) error { return nil }, translateRule)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+FieldErrors() - раскладывает ошибку валидатора по полям с сообщениями на языке переводчика.
+Для ошибок, которые пришли не от валидатора, отдает nil.
+*/
+func FieldErrors(err error, trans ut.Translator) []FieldError {
+	var ve validator.ValidationErrors
+	if !errors.As(err, &ve) {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(ve))
+	for _, fe := range ve {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fe.Translate(trans),
+		})
+	}
+
+	return fields
+}
+
+// fieldName() - имя поля из тэга json или query, иначе имя поля структуры
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "query"} {
+		name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return f.Name
+}
+
+// translateRule() - текст ошибки по нарушенному правилу на языке переводчика
+func translateRule(trans ut.Translator, fe validator.FieldError) string {
+	param := fieldParam(fe)
+
+	key := "rule." + fe.Tag()
+	switch fe.Tag() {
+	case "oneof":
+		param = strings.ReplaceAll(param, " ", ", ")
+	case "gte":
+		key = "rule.min"
+	case "lte":
+		key = "rule.max"
+	}
+
+	if (key == "rule.min" || key == "rule.max") && fe.Kind() == reflect.String {
+		key += ".string"
+	}
+
+	text, err := trans.T(key, fe.Field(), param)
+	if err != nil {
+		return fmt.Sprintf("%s failed on the %s rule", fe.Field(), fe.Tag())
+	}
+
+	return text
+}
+
+/*
+fieldParam() - параметр правила в том виде, в каком его пишет клиент.
+Числовые типы со своим String() (например, models.Decimal в сотых)
+получают параметр в своем формате, а не в сырых единицах хранения.
+*/
+func fieldParam(fe validator.FieldError) string {
+	param := fe.Param()
+	if fe.Kind() != reflect.Int64 || fe.Type() == nil {
+		return param
+	}
+
+	n, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return param
+	}
+
+	v := reflect.ValueOf(n).Convert(fe.Type())
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return param
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/JohanVong/online_bazaar/internal/i18n"
+)
+
+// cents - число в сотых со своим String(), как models.Decimal
+type cents int64
+
+func (c cents) String() string {
+	return fmt.Sprintf("%d.%02d", c/100, c%100)
+}
+
+type testInput struct {
+	Name   string `json:"Name" validate:"required,max=5"`
+	Email  string `json:"Email,omitempty" validate:"email"`
+	Price  cents  `json:"Price" validate:"gt=150"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=asc desc"`
+	Amount int    `validate:"min=1"`
+}
+
+func TestFieldErrors(t *testing.T) {
+	catalog, err := i18n.New("en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewCustomValidator()
+	if err = v.RegisterTranslations(catalog.Translators()...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input testInput
+		lang  string
+		want  []FieldError
+	}{
+		{ // valid input
+			testInput{Name: "Test", Email: "test@mail.test", Price: 151, Amount: 1},
+			"",
+			nil,
+		},
+		{ // every field is wrong, names are taken from tags
+			testInput{Email: "test", Price: 150, Sort: "random"},
+			"en",
+			[]FieldError{
+				{"Name", "required", "Name is required"},
+				{"Email", "email", "Email must be a valid email address"},
+				{"Price", "gt", "Price must be greater than 1.50"},
+				{"sort", "oneof", "sort must be one of: asc, desc"},
+				{"Amount", "min", "Amount must be at least 1"},
+			},
+		},
+		{ // string length
+			testInput{Name: "Too long", Email: "test@mail.test", Price: 151, Amount: 1},
+			"en",
+			[]FieldError{
+				{"Name", "max", "Name must be at most 5 characters long"},
+			},
+		},
+		{ // same errors in russian
+			testInput{Name: "Too long", Email: "test", Price: 151, Sort: "random", Amount: 1},
+			"ru",
+			[]FieldError{
+				{"Name", "max", "Поле Name должно содержать не больше 5 символов"},
+				{"Email", "email", "Поле Email должно быть корректным email"},
+				{"sort", "oneof", "Поле sort должно быть одним из: asc, desc"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		err := v.Validate(&tt.input)
+		if tt.want == nil {
+			assert.NoError(t, err)
+			continue
+		}
+
+		assert.Equal(t, tt.want, FieldErrors(err, catalog.Translator(tt.lang)))
+	}
+
+	assert.Nil(t, FieldErrors(errors.New("not a validation error"), catalog.Translator("")))
+}
+
+// testPatch - входящие данные частичного обновления
+type testPatch struct {
+	Name  string `json:"Name" validate:"required"`
+	Email string `json:"Email" validate:"email"`
+	sent  []string
+}
+
+func (tp *testPatch) Present() []string {
+	return tp.sent
+}
+
+func TestValidatePartial(t *testing.T) {
+	v := NewCustomValidator()
+
+	tests := []struct {
+		input     testPatch
+		wantError bool
+	}{
+		{ // nothing is sent, nothing is checked
+			testPatch{},
+			false,
+		},
+		{ // only sent fields are checked
+			testPatch{Email: "test@mail.test", sent: []string{"Email"}},
+			false,
+		},
+		{ // sent field is wrong
+			testPatch{Email: "test", sent: []string{"Email"}},
+			true,
+		},
+		{ // sent empty field
+			testPatch{sent: []string{"Name"}},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := v.Validate(&tt.input)
+		assert.Equal(t, tt.wantError, err != nil, tt.input.sent)
+	}
+}